@@ -36,9 +36,12 @@ const
   DefaultConcurrentAdvertRequests = 10
   DefaultAdvertiseLoopSleep = 30.minutes
 
+type AdvertiseFilter* = proc(cid: Cid): bool {.gcsafe, raises: [].}
+
 type Advertiser* = ref object of RootObj
   localStore*: BlockStore # Local block store for this instance
   discovery*: Discovery # Discovery interface
+  filter*: ?AdvertiseFilter # Returns false for the manifests not to advertise
 
   advertiserRunning*: bool # Indicates if discovery is running
   concurrentAdvReqs: int # Concurrent advertise requests
@@ -63,6 +66,10 @@ proc advertiseBlock(b: Advertiser, cid: Cid) {.async: (raises: [CancelledError])
 
   try:
     if isM:
+      if filter =? b.filter and not filter(cid):
+        trace "Manifest not advertised", cid
+        return
+
       without blk =? await b.localStore.getBlock(cid), err:
         error "Error retrieving manifest block", cid, err = err.msg
         return
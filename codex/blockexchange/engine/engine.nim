@@ -100,6 +100,7 @@ type
   TaskScheduler* = proc(task: BlockExcPeerCtx): bool {.gcsafe.}
   PeerSelector* =
     proc(peers: seq[BlockExcPeerCtx]): BlockExcPeerCtx {.gcsafe, raises: [].}
+  PeerFilter* = proc(peer: PeerId): bool {.gcsafe, raises: [].}
 
   BlockExcEngine* = ref object of RootObj
     localStore*: BlockStore # Local block store for this instance
@@ -117,6 +118,8 @@ type
     pricing*: ?Pricing # Optional bandwidth pricing
     discovery*: DiscoveryEngine
     advertiser*: Advertiser
+    servedPeers*: ?HashSet[PeerId] # Only peers served blocks when set
+    peerFilter*: ?PeerFilter # Peers served blocks when it returns true
     lastDiscRequest: Moment # time of last discovery request
 
   Pricing* = object
@@ -750,6 +753,8 @@ proc blocksDeliveryHandler*(
   codex_block_exchange_blocks_received.inc(validatedBlocksDelivery.len.int64)
 
   if peerCtx != nil:
+    peerCtx.blocksValidated(validatedBlocksDelivery)
+
     if err =? catch(await self.payForBlocks(peerCtx, blocksDelivery)).errorOption:
       warn "Error paying for blocks", err = err.msg
       return
@@ -763,6 +768,14 @@ proc wantListHandler*(
 ) {.async: (raises: []).} =
   trace "Received want list from peer", peer, wantList = wantList.entries.len
 
+  if servedPeers =? self.servedPeers and peer notin servedPeers:
+    trace "Ignoring want list of peer not served", peer
+    return
+
+  if filter =? self.peerFilter and not filter(peer):
+    trace "Ignoring want list of rejected peer", peer
+    return
+
   let peerCtx = self.peers.get(peer)
 
   if peerCtx.isNil:
@@ -959,6 +972,7 @@ proc taskHandler*(
 
       await self.network.request.sendBlocksDelivery(peerCtx.id, blockDeliveries)
       codex_block_exchange_blocks_sent.inc(blockDeliveries.len.int64)
+      peerCtx.blocksDelivered(blockDeliveries)
       # Drops the batch from the peer's set of wanted blocks; i.e. assumes that after
       # we send the blocks, then the peer no longer wants them, so we don't need to
       # re-send them. Note that the send might still fail down the line and we will
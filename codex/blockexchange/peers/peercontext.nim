@@ -30,6 +30,12 @@ const
   MaxRefreshBackoff = 36 # 36 seconds
   MaxWantListBatchSize* = 1024 # Maximum blocks to send per WantList message
 
+type BlockExcPeerStats* = object
+  blocksSent*: int # blocks delivered to the peer
+  bytesSent*: int # bytes of the blocks delivered to the peer
+  blocksReceived*: int # valid blocks received from the peer
+  bytesReceived*: int # bytes of the valid blocks received from the peer
+
 type BlockExcPeerCtx* = ref object of RootObj
   id*: PeerId
   blocks*: Table[BlockAddress, Presence] # remote peer have list including price
@@ -46,6 +52,7 @@ type BlockExcPeerCtx* = ref object of RootObj
   activityTimeout*: Duration
   lastSentWants*: HashSet[BlockAddress]
     # track what wantList we last sent for delta updates
+  stats*: BlockExcPeerStats # blocks exchanged with the peer
 
 proc isKnowledgeStale*(self: BlockExcPeerCtx): bool =
   let staleness =
@@ -131,6 +138,18 @@ proc blockReceived*(self: BlockExcPeerCtx, address: BlockAddress): bool =
   self.lastExchange = Moment.now()
   wasRequested
 
+proc blocksDelivered*(self: BlockExcPeerCtx, blocksDelivery: seq[BlockDelivery]) =
+  ## Counts the blocks sent to the peer.
+  self.stats.blocksSent += blocksDelivery.len
+  for bd in blocksDelivery:
+    self.stats.bytesSent += bd.blk.data.len
+
+proc blocksValidated*(self: BlockExcPeerCtx, blocksDelivery: seq[BlockDelivery]) =
+  ## Counts the valid blocks received from the peer.
+  self.stats.blocksReceived += blocksDelivery.len
+  for bd in blocksDelivery:
+    self.stats.bytesReceived += bd.blk.data.len
+
 proc activityTimer*(
     self: BlockExcPeerCtx
 ): Future[void] {.async: (raises: [CancelledError]).} =
@@ -11,6 +11,7 @@ import std/sequtils
 import std/strutils
 import std/os
 import std/tables
+import std/sets
 import std/cpuinfo
 
 import pkg/chronos
@@ -45,6 +46,7 @@ import ./namespaces
 import ./codextypes
 import ./logutils
 import ./nat
+import ./peergater
 
 logScope:
   topics = "codex node"
@@ -55,6 +57,7 @@ type
     restServer: RestServerRef
     codexNode: CodexNodeRef
     repoStore: RepoStore
+    peerGater: PeerGater
     maintenance: BlockMaintainer
     taskpool: Taskpool
     isStarted: bool
@@ -71,6 +74,9 @@ func node*(self: CodexServer): CodexNodeRef =
 func repoStore*(self: CodexServer): RepoStore =
   return self.repoStore
 
+func peerGater*(self: CodexServer): PeerGater =
+  return self.peerGater
+
 proc waitForSync(provider: Provider): Future[void] {.async.} =
   var sleepTime = 1
   trace "Checking sync state of Ethereum provider..."
@@ -256,6 +262,8 @@ proc new*(
     .withTcpTransport({ServerFlags.ReuseAddr, ServerFlags.TcpNoDelay})
     .build()
 
+  let peerGater = PeerGater.new(switch, config.allowedPeers, config.deniedPeers)
+
   var
     cache: CacheStore = nil
     taskpool: Taskpool
@@ -362,6 +370,21 @@ proc new*(
       taskPool = taskpool,
     )
 
+  # The connections of the rejected peers are closed after they are
+  # established, their want lists are ignored until then
+  engine.peerFilter = some PeerFilter(
+    proc(peer: PeerId): bool =
+      peerGater.isAllowed(peer)
+  )
+
+  if config.privateMode:
+    # The datasets are not announced and only the given peers retrieve blocks
+    advertiser.filter = some AdvertiseFilter(
+      proc(cid: Cid): bool =
+        false
+    )
+    engine.servedPeers = some config.privatePeers.toHashSet
+
   var restServer: RestServerRef = nil
 
   if config.apiBindAddress.isSome:
@@ -381,6 +404,7 @@ proc new*(
     codexNode: codexNode,
     restServer: restServer,
     repoStore: repoStore,
+    peerGater: peerGater,
     maintenance: maintenance,
     taskpool: taskpool,
   )
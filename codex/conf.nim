@@ -77,6 +77,8 @@ const
   codex_enable_log_counter* {.booldefine.} = false
 
   DefaultThreadCount* = ThreadCount(0)
+  DefaultUploadSessionTtl* = 1.days
+  DefaultUploadSessionIdleTimeout* = 1.hours
 
 type
   StartUpCmd* {.pure.} = enum
@@ -190,6 +192,31 @@ type
       name: "max-peers"
     .}: int
 
+    privateMode* {.
+      desc:
+        "Do not announce the datasets to the network and only serve " &
+        "blocks to the peers given with --private-peer",
+      defaultValue: false,
+      name: "private-mode"
+    .}: bool
+
+    privatePeers* {.
+      desc: "Peer ID of a peer allowed to retrieve blocks in private mode",
+      name: "private-peer"
+    .}: seq[PeerId]
+
+    allowedPeers* {.
+      desc:
+        "Peer ID of a peer allowed to connect, the connections of the other " &
+        "peers are closed once set up when set",
+      name: "allowed-peer"
+    .}: seq[PeerId]
+
+    deniedPeers* {.
+      desc: "Peer ID of a peer whose connections are closed once set up",
+      name: "denied-peer"
+    .}: seq[PeerId]
+
     numThreads* {.
       desc:
         "Number of worker threads (\"0\" = use as many threads as there are CPU cores available)",
@@ -286,6 +313,27 @@ type
       desc: "Logs to file", defaultValue: string.none, name: "log-file", hidden
     .}: Option[string]
 
+    uploadSessionTtl* {.
+      desc:
+        "Time after the last stored block after which an interrupted " &
+        "upload session of the library expires and cannot be resumed - " &
+        "0 disables the expiry",
+      defaultValue: DefaultUploadSessionTtl,
+      defaultValueDesc: $DefaultUploadSessionTtl,
+      name: "upload-session-ttl",
+      hidden
+    .}: Duration
+
+    uploadSessionIdleTimeout* {.
+      desc:
+        "Time without activity after which an upload session of the library " &
+        "is cancelled - 0 disables the timeout",
+      defaultValue: DefaultUploadSessionIdleTimeout,
+      defaultValueDesc: $DefaultUploadSessionIdleTimeout,
+      name: "upload-session-idle-timeout",
+      hidden
+    .}: Duration
+
     case cmd* {.defaultValue: noCmd, command.}: StartUpCmd
     of persistence:
       ethProvider* {.
@@ -561,6 +609,19 @@ proc parseCmdArg*(T: type SignedPeerRecord, uri: string): T =
     quit QuitFailure
   return res.get()
 
+func parse*(T: type PeerId, p: string): Result[PeerId, string] =
+  let res = PeerId.init(p)
+  if res.isErr:
+    return err("Not a valid peer ID: " & p)
+  return ok(res.get())
+
+proc parseCmdArg*(T: type PeerId, p: string): T =
+  let res = PeerId.parse(p)
+  if res.isErr:
+    fatal "Cannot parse the peer ID.", error = res.error(), input = p
+    quit QuitFailure
+  return res.get()
+
 func parse*(T: type NatConfig, p: string): Result[NatConfig, string] =
   case p.toLowerAscii
   of "any":
@@ -676,6 +737,15 @@ proc readValue*(
     quit QuitFailure
   val = dur
 
+proc readValue*(
+    r: var TomlReader, val: var PeerId
+) {.raises: [SerializationError].} =
+  val =
+    try:
+      parseCmdArg(PeerId, r.readValue(string))
+    except CatchableError as err:
+      raise newException(SerializationError, err.msg)
+
 proc readValue*(
     r: var TomlReader, val: var NatConfig
 ) {.raises: [SerializationError].} =
@@ -698,6 +768,9 @@ proc completeCmdArg*(T: type Duration, val: string): seq[string] =
 proc completeCmdArg*(T: type ThreadCount, val: string): seq[string] =
   discard
 
+proc completeCmdArg*(T: type PeerId, val: string): seq[string] =
+  discard
+
 # silly chronicles, colors is a compile-time property
 proc stripAnsi*(v: string): string =
   var
@@ -85,6 +85,7 @@ type
     gcsafe, async: (raises: [CancelledError])
   .}
   OnBlockStoredProc = proc(chunk: seq[byte]): void {.gcsafe, raises: [].}
+  OnBlockCidProc = proc(cid: Cid): void {.gcsafe, raises: [].}
 
 func switch*(self: CodexNodeRef): Switch =
   return self.switch
@@ -99,8 +100,12 @@ func discovery*(self: CodexNodeRef): Discovery =
   return self.discovery
 
 proc storeManifest*(
-    self: CodexNodeRef, manifest: Manifest
+    self: CodexNodeRef, manifest: Manifest, onManifestCid: OnBlockCidProc = nil
 ): Future[?!bt.Block] {.async.} =
+  ## Store the manifest block. `onManifestCid` is called with the cid
+  ## of the manifest before it is stored.
+  ##
+
   without encodedVerifiable =? manifest.encode(), err:
     trace "Unable to encode manifest"
     return failure(err)
@@ -109,6 +114,9 @@ proc storeManifest*(
     trace "Unable to create block from manifest"
     return failure(error)
 
+  if not onManifestCid.isNil:
+    onManifestCid(blk.cid)
+
   if err =? (await self.networkStore.putBlock(blk)).errorOption:
     trace "Unable to store manifest block", cid = blk.cid, err = err.msg
     return failure(err)
@@ -429,6 +437,17 @@ proc delete*(
 
   await self.deleteEntireDataset(cid)
 
+proc releaseBlocks*(self: CodexNodeRef, cids: seq[Cid]) {.async: (raises: []).} =
+  ## Deletes the blocks stored by an upload which did not complete.
+  ## The blocks referenced by another dataset are kept: the store
+  ## refuses to delete them.
+  for cid in cids:
+    try:
+      if err =? (await self.networkStore.delBlock(cid)).errorOption:
+        trace "Block of the cancelled upload not released", cid, err = err.msg
+    except CancelledError:
+      return
+
 proc store*(
     self: CodexNodeRef,
     stream: LPStream,
@@ -436,18 +455,27 @@ proc store*(
     mimetype: ?string = string.none,
     blockSize = DefaultBlockSize,
     onBlockStored: OnBlockStoredProc = nil,
+    stored: seq[Cid] = @[],
+    onBlockCid: OnBlockCidProc = nil,
+    onManifestCid: OnBlockCidProc = nil,
 ): Future[?!Cid] {.async.} =
   ## Save stream contents as dataset with given blockSize
   ## to nodes's BlockStore, and return Cid of its manifest
   ##
-  info "Storing data"
+  ## When resuming an interrupted upload, `stored` contains the cids of
+  ## the blocks already stored, all of them of blockSize bytes, and the
+  ## stream continues after them. `onBlockCid` is called with the cid
+  ## of each block stored and `onManifestCid` with the cid of the
+  ## manifest, before it is stored and announced.
+  ##
+  info "Storing data", resumedBlocks = stored.len
 
   let
     hcodec = Sha256HashCodec
     dataCodec = BlockCodec
     chunker = LPStreamChunker.new(stream, chunkSize = blockSize)
 
-  var cids: seq[Cid]
+  var cids = stored
 
   try:
     while (let chunk = await chunker.getBytes(); chunk.len > 0):
@@ -466,9 +494,14 @@ proc store*(
         error "Unable to store block", cid = blk.cid, err = err.msg
         return failure(&"Unable to store block {blk.cid}")
 
+      if not onBlockCid.isNil:
+        onBlockCid(cid)
+
       if not onBlockStored.isNil:
         onBlockStored(chunk)
   except CancelledError as exc:
+    info "Storing data cancelled, releasing the stored blocks", blocks = cids.len
+    await noCancel self.releaseBlocks(cids)
     raise exc
   except CatchableError as exc:
     return failure(exc.msg)
@@ -492,7 +525,7 @@ proc store*(
   let manifest = Manifest.new(
     treeCid = treeCid,
     blockSize = blockSize,
-    datasetSize = NBytes(chunker.offset),
+    datasetSize = NBytes(stored.len * blockSize.int + chunker.offset),
     version = CIDv1,
     hcodec = hcodec,
     codec = dataCodec,
@@ -500,7 +533,7 @@ proc store*(
     mimetype = mimetype,
   )
 
-  without manifestBlk =? await self.storeManifest(manifest), err:
+  without manifestBlk =? await self.storeManifest(manifest, onManifestCid), err:
     error "Unable to store manifest"
     return failure(err)
 
@@ -0,0 +1,105 @@
+## Nim-Codex
+## Copyright (c) 2025 Status Research & Development GmbH
+## Licensed under either of
+##  * Apache License, version 2.0, ([LICENSE-APACHE](LICENSE-APACHE))
+##  * MIT license ([LICENSE-MIT](LICENSE-MIT))
+## at your option.
+## This file may not be copied, modified, or distributed except according to
+## those terms.
+
+{.push raises: [].}
+
+import std/sets
+
+import pkg/chronos
+import pkg/libp2p
+
+import ./logutils
+
+logScope:
+  topics = "codex peergater"
+
+type
+  OnPeerRejected* = proc(peerId: PeerId, incoming: bool) {.gcsafe, raises: [].}
+
+  PeerGater* = ref object
+    ## Gates the connections of the switch. The connections of the
+    ## rejected peers are closed once the switch reports them as
+    ## connected, the protocols started meanwhile, like identify, may
+    ## still run. The block exchange engine checks `isAllowed` too, so
+    ## a rejected peer is never served blocks.
+    switch: Switch
+    allowed: HashSet[PeerId] # Only peers accepted when restricted
+    denied: HashSet[PeerId] # Peers always rejected
+    restricted: bool # Set when the allowed peers are configured
+    onRejected*: OnPeerRejected # Called for each connection rejected
+
+func isAllowed*(self: PeerGater, peerId: PeerId): bool =
+  if peerId in self.denied:
+    return false
+
+  return not self.restricted or peerId in self.allowed
+
+proc close(self: PeerGater, peerId: PeerId) {.async: (raises: []).} =
+  try:
+    await self.switch.disconnect(peerId)
+  except CancelledError:
+    trace "Cancelled disconnecting peer", peerId
+
+proc allow*(self: PeerGater, peerId: PeerId) =
+  ## Accepts the connections of the peer, it is removed from the
+  ## denied peers.
+  ##
+
+  self.denied.excl(peerId)
+  self.allowed.incl(peerId)
+
+  trace "Peer allowed", peerId
+
+proc deny*(self: PeerGater, peerId: PeerId) {.async: (raises: [CancelledError]).} =
+  ## Rejects the connections of the peer and closes its current ones.
+  ## The connections are closed after they are set up, not refused.
+  ##
+
+  self.allowed.excl(peerId)
+  self.denied.incl(peerId)
+
+  trace "Peer denied", peerId
+
+  await self.switch.disconnect(peerId)
+
+proc new*(
+    T: type PeerGater,
+    switch: Switch,
+    allowed: seq[PeerId] = @[],
+    denied: seq[PeerId] = @[],
+): PeerGater =
+  ## Create a gater of the connections of the switch. When `allowed`
+  ## is not empty, only the allowed peers are accepted, including the
+  ## ones allowed later with `allow`.
+  ##
+
+  let self = PeerGater(
+    switch: switch,
+    allowed: allowed.toHashSet,
+    denied: denied.toHashSet,
+    restricted: allowed.len > 0,
+  )
+
+  proc onConnected(
+      peerId: PeerId, event: ConnEvent
+  ): Future[void] {.gcsafe, async: (raises: [CancelledError]).} =
+    if self.isAllowed(peerId):
+      return
+
+    info "Connection rejected", peerId, incoming = event.incoming
+
+    if not self.onRejected.isNil:
+      self.onRejected(peerId, event.incoming)
+
+    # Closed outside of the connection event, which the switch awaits
+    asyncSpawn self.close(peerId)
+
+  switch.addConnEventHandler(onConnected, ConnEventKind.Connected)
+
+  return self
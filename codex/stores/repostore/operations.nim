@@ -139,7 +139,14 @@ proc updateBlockMetadata*(
     plusRefCount: Natural = 0,
     minusRefCount: Natural = 0,
     minExpiry: SecondsSince1970 = 0,
+    expiry: SecondsSince1970 = 0,
 ): Future[?!void] {.async: (raises: [CancelledError]).} =
+  ## Updates the metadata of a block. The expiry is raised to `minExpiry`
+  ## or, when `expiry` is larger than zero, replaced by it. The expiry of
+  ## a block referenced by several datasets is never lowered, it would
+  ## expire the datasets still using it.
+  ##
+
   if cid.isEmpty:
     return success()
 
@@ -152,7 +159,13 @@ proc updateBlockMetadata*(
       if currBlockMd =? maybeCurrBlockMd:
         BlockMetadata(
           size: currBlockMd.size,
-          expiry: max(currBlockMd.expiry, minExpiry),
+          expiry:
+            if expiry > 0 and currBlockMd.refCount <= 1:
+              expiry
+            elif expiry > 0:
+              max(currBlockMd.expiry, expiry)
+            else:
+              max(currBlockMd.expiry, minExpiry),
           refCount: currBlockMd.refCount + plusRefCount - minusRefCount,
         ).some
       else:
@@ -135,6 +135,61 @@ method ensureExpiry*(
 
   await self.ensureExpiry(leafMd.blkCid, expiry)
 
+proc setExpiry*(
+    self: RepoStore, cid: Cid, expiry: SecondsSince1970
+): Future[?!void] {.async: (raises: [CancelledError]).} =
+  ## Set the block's associated expiry to the given timestamp,
+  ## even if it is lower than the current one, unless the block is
+  ## referenced by several datasets
+  ##
+
+  if expiry <= 0:
+    return
+      failure(newException(ValueError, "Expiry timestamp must be larger then zero"))
+
+  await self.updateBlockMetadata(cid, expiry = expiry)
+
+proc setExpiry*(
+    self: RepoStore, treeCid: Cid, index: Natural, expiry: SecondsSince1970
+): Future[?!void] {.async: (raises: [CancelledError]).} =
+  ## Set the block's associated expiry to the given timestamp,
+  ## even if it is lower than the current one, unless the block is
+  ## referenced by several datasets
+  ##
+
+  without leafMd =? await self.getLeafMetadata(treeCid, index), err:
+    return failure(err)
+
+  await self.setExpiry(leafMd.blkCid, expiry)
+
+proc getExpiry*(
+    self: RepoStore, cid: Cid
+): Future[?!SecondsSince1970] {.async: (raises: [CancelledError]).} =
+  ## Returns the block's associated expiry
+  ##
+
+  without key =? createBlockExpirationMetadataKey(cid), err:
+    return failure(err)
+
+  without md =? await get[BlockMetadata](self.metaDs, key), err:
+    if err of DatastoreKeyNotFound:
+      return failure(newException(BlockNotFoundError, err.msg))
+    else:
+      return failure(err)
+
+  return success(md.expiry)
+
+proc getExpiry*(
+    self: RepoStore, treeCid: Cid, index: Natural
+): Future[?!SecondsSince1970] {.async: (raises: [CancelledError]).} =
+  ## Returns the block's associated expiry
+  ##
+
+  without leafMd =? await self.getLeafMetadata(treeCid, index), err:
+    return failure(err)
+
+  await self.getExpiry(leafMd.blkCid)
+
 method putCidAndProof*(
     self: RepoStore, treeCid: Cid, index: Natural, blkCid: Cid, proof: CodexProof
 ): Future[?!void] {.async: (raises: [CancelledError]).} =
@@ -0,0 +1,169 @@
+package codex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of calls in flight of the batch
+// operations.
+const DefaultBatchConcurrency = 8
+
+// BatchOptions configures ExistsMany, DeleteMany, FetchMany and
+// their streaming variants.
+type BatchOptions struct {
+	// Concurrency is the maximum number of calls in flight.
+	// Default is DefaultBatchConcurrency.
+	Concurrency int
+}
+
+func (o BatchOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultBatchConcurrency
+	}
+
+	return o.Concurrency
+}
+
+// BatchResult is the result of the call made for a CID by a batch operation.
+type BatchResult[T any] struct {
+	Cid   string
+	Value T
+	Err   error
+}
+
+// batchStream calls call for each CID, with up to options.Concurrency calls
+// in flight, and yields the results as they complete. The duplicate CIDs are
+// called once. Once the context is done, no call is started and the results
+// of the remaining CIDs hold the context error. Breaking out of the loop
+// waits for the calls in flight.
+func batchStream[T any](ctx context.Context, cids []string, options BatchOptions, call func(cid string) (T, error)) iter.Seq[BatchResult[T]] {
+	return func(yield func(BatchResult[T]) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make(chan BatchResult[T])
+		slots := make(chan struct{}, options.concurrency())
+
+		go func() {
+			var wg sync.WaitGroup
+			defer close(results)
+			defer wg.Wait()
+
+			seen := make(map[string]bool, len(cids))
+			for _, cid := range cids {
+				if seen[cid] {
+					continue
+				}
+				seen[cid] = true
+
+				if ctx.Err() == nil {
+					select {
+					case slots <- struct{}{}:
+					case <-ctx.Done():
+					}
+				}
+
+				if ctx.Err() != nil {
+					results <- BatchResult[T]{Cid: cid, Err: context.Cause(ctx)}
+					continue
+				}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					value, err := call(cid)
+					<-slots
+
+					results <- BatchResult[T]{Cid: cid, Value: value, Err: err}
+				}()
+			}
+		}()
+
+		for result := range results {
+			if !yield(result) {
+				cancel()
+
+				// Let the goroutines end
+				for range results {
+				}
+
+				return
+			}
+		}
+	}
+}
+
+// collect gathers the results in a map and joins the errors,
+// each one prefixed with its CID. The CIDs in error are not in the map.
+func collect[T any](results iter.Seq[BatchResult[T]]) (map[string]T, error) {
+	values := map[string]T{}
+	var errs []error
+
+	for result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Cid, result.Err))
+			continue
+		}
+
+		values[result.Cid] = result.Value
+	}
+
+	return values, errors.Join(errs...)
+}
+
+// ExistsStream checks if each CID is available in the local store of the
+// node and yields the results as they complete.
+func ExistsStream(ctx context.Context, node Node, cids []string, options BatchOptions) iter.Seq[BatchResult[bool]] {
+	return batchStream(ctx, cids, options, node.Exists)
+}
+
+// ExistsMany checks if each CID is available in the local store of the node.
+// The map has the result of each CID checked, the errors of the others are
+// joined in the returned error.
+func ExistsMany(ctx context.Context, node Node, cids []string, options BatchOptions) (map[string]bool, error) {
+	return collect(ExistsStream(ctx, node, cids, options))
+}
+
+// DeleteStream deletes each CID from the node and yields the results
+// as they complete.
+func DeleteStream(ctx context.Context, node Node, cids []string, options BatchOptions) iter.Seq[BatchResult[struct{}]] {
+	return batchStream(ctx, cids, options, func(cid string) (struct{}, error) {
+		return struct{}{}, node.Delete(cid)
+	})
+}
+
+// DeleteMany deletes each CID from the node. The map has the error of each
+// CID, nil when it was deleted, and the errors are also joined in the
+// returned error.
+func DeleteMany(ctx context.Context, node Node, cids []string, options BatchOptions) (map[string]error, error) {
+	results := map[string]error{}
+	var errs []error
+
+	for result := range DeleteStream(ctx, node, cids, options) {
+		results[result.Cid] = result.Err
+
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Cid, result.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// FetchStream starts the download of each CID to the node and yields
+// the manifests as they are retrieved.
+func FetchStream(ctx context.Context, node Node, cids []string, options BatchOptions) iter.Seq[BatchResult[Manifest]] {
+	return batchStream(ctx, cids, options, node.Fetch)
+}
+
+// FetchMany starts the download of each CID to the node, see Node.Fetch.
+// The map has the manifest of each CID retrieved, the errors of the others
+// are joined in the returned error.
+func FetchMany(ctx context.Context, node Node, cids []string, options BatchOptions) (map[string]Manifest, error) {
+	return collect(FetchStream(ctx, node, cids, options))
+}
@@ -0,0 +1,122 @@
+package codex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/memory"
+)
+
+// slowNode records the maximum number of Exists calls in flight.
+type slowNode struct {
+	*memory.Node
+	inFlight atomic.Int32
+	max      atomic.Int32
+}
+
+func (node *slowNode) Exists(cid string) (bool, error) {
+	n := node.inFlight.Add(1)
+	defer node.inFlight.Add(-1)
+
+	for {
+		m := node.max.Load()
+		if n <= m || node.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	return node.Node.Exists(cid)
+}
+
+func uploadMany(t *testing.T, node codex.Node, n int) []string {
+	t.Helper()
+
+	cids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		cid, err := node.UploadReader(codex.UploadOptions{}, bytes.NewReader([]byte(strings.Repeat("x", i+1))))
+		if err != nil {
+			t.Fatalf("failed to upload: %v", err)
+		}
+
+		cids = append(cids, cid)
+	}
+
+	return cids
+}
+
+func TestExistsManyLimitsConcurrency(t *testing.T) {
+	node := &slowNode{Node: memory.New()}
+	cids := uploadMany(t, node, 20)
+
+	unknown := "zdj7WWeQ43G6JJvLWQWZpyHuAMq6uYWRjkBXFad11vE2LHhQ7"
+	results, err := codex.ExistsMany(context.Background(), node, append(cids, unknown, cids[0]), codex.BatchOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatalf("failed to check the cids: %v", err)
+	}
+
+	if len(results) != 21 || results[unknown] {
+		t.Fatalf("unexpected results: %v", results)
+	}
+
+	for _, cid := range cids {
+		if !results[cid] {
+			t.Fatalf("expected %s to exist", cid)
+		}
+	}
+
+	if max := node.max.Load(); max > 3 || max < 2 {
+		t.Fatalf("expected up to 3 calls in flight, got %d", max)
+	}
+}
+
+func TestDeleteAndFetchMany(t *testing.T) {
+	node := memory.New()
+	cids := uploadMany(t, node, 5)
+
+	deleted, err := codex.DeleteMany(context.Background(), node, cids[:2], codex.BatchOptions{})
+	if err != nil || len(deleted) != 2 || deleted[cids[0]] != nil {
+		t.Fatalf("unexpected delete results: %v, err=%v", deleted, err)
+	}
+
+	manifests, err := codex.FetchMany(context.Background(), node, cids, codex.BatchOptions{})
+	if err == nil || !strings.Contains(err.Error(), cids[0]) || !strings.Contains(err.Error(), cids[1]) {
+		t.Fatalf("expected the errors of the deleted cids, got %v", err)
+	}
+
+	if len(manifests) != 3 || manifests[cids[4]].Cid != cids[4] {
+		t.Fatalf("unexpected manifests: %v", manifests)
+	}
+}
+
+func TestExistsStreamCancelled(t *testing.T) {
+	node := &slowNode{Node: memory.New()}
+	cids := uploadMany(t, node, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	count := 0
+	for result := range codex.ExistsStream(ctx, node, cids, codex.BatchOptions{Concurrency: 2}) {
+		count++
+
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("expected the context error, got %v", result.Err)
+		}
+	}
+
+	if count != len(cids) {
+		t.Fatalf("expected a result for each cid, got %d", count)
+	}
+
+	// Breaking out of the loop stops the batch
+	for range codex.ExistsStream(context.Background(), node, cids, codex.BatchOptions{Concurrency: 2}) {
+		break
+	}
+}
@@ -0,0 +1,248 @@
+// Package codextest starts Codex nodes embedded in the test process,
+// for the integration tests of the code using the Go bindings.
+//
+// Each node gets its own temporary data dir and free TCP and UDP ports
+// on the loopback interface. The nodes are connected together, waited
+// for readiness and destroyed when the test ends:
+//
+//	nodes := codextest.StartNodes(t, 2, codextest.Options{})
+//	cid, _ := nodes[0].UploadReader(codex.UploadOptions{}, data)
+//	nodes[1].DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf})
+//
+// The package requires libcodex.so, like the libcodex package.
+package codextest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/libcodex"
+)
+
+// DefaultReadyTimeout is the time given to the nodes to become ready.
+const DefaultReadyTimeout = 30 * time.Second
+
+// Mode is the way the nodes are connected to each other.
+type Mode int
+
+const (
+	// The first node SPR is used as bootstrap node by the other nodes,
+	// so that they find each other through the DHT.
+	ModeBootstrap Mode = iota
+
+	// The other nodes call Connect with the first node peer id and
+	// addresses. The DHT is not used, only the first node knows the others.
+	ModeConnect
+)
+
+// Options configures the nodes started by StartNodes.
+type Options struct {
+	// Config is the base configuration of every node.
+	// DataDir, ListenAddrs, DiscoveryPort and BootstrapNodes are
+	// overwritten for each node. LogLevel defaults to ERROR and Nat
+	// to extip:127.0.0.1.
+	Config libcodex.Config
+
+	// Mode is the way the nodes are connected. Default is ModeBootstrap.
+	Mode Mode
+
+	// Ready are the conditions waited for on every node after the start.
+	// When nil, the nodes wait for one peer in their routing table
+	// in ModeBootstrap and are not waited for in ModeConnect.
+	Ready *codex.ReadyOptions
+
+	// ReadyTimeout is the time given to each node to become ready.
+	// Default is DefaultReadyTimeout.
+	ReadyTimeout time.Duration
+}
+
+func (o Options) ready(n int) *codex.ReadyOptions {
+	if o.Ready != nil {
+		return o.Ready
+	}
+
+	if o.Mode == ModeBootstrap && n > 1 {
+		return &codex.ReadyOptions{MinPeers: 1}
+	}
+
+	return nil
+}
+
+func (o Options) readyTimeout() time.Duration {
+	if o.ReadyTimeout <= 0 {
+		return DefaultReadyTimeout
+	}
+
+	return o.ReadyTimeout
+}
+
+// StartNodes starts n nodes, connects them to the first one and waits
+// for their readiness. It fails the test if a node cannot be started.
+// The nodes are stopped and destroyed in t.Cleanup.
+func StartNodes(t testing.TB, n int, options Options) []*libcodex.CodexNode {
+	t.Helper()
+
+	nodes := make([]*libcodex.CodexNode, 0, n)
+
+	for i := 0; i < n; i++ {
+		config := nodeConfig(t, options.Config)
+
+		if i > 0 && options.Mode == ModeBootstrap {
+			spr, err := nodes[0].Spr()
+			if err != nil {
+				t.Fatalf("failed to get the SPR of node 0: %v", err)
+			}
+
+			config.BootstrapNodes = []string{spr}
+		}
+
+		nodes = append(nodes, StartNode(t, config))
+	}
+
+	if options.Mode == ModeConnect && n > 1 {
+		connect(t, nodes)
+	}
+
+	if ready := options.ready(n); ready != nil {
+		for i, node := range nodes {
+			ctx, cancel := context.WithTimeout(context.Background(), options.readyTimeout())
+			err := node.WaitReady(ctx, *ready)
+			cancel()
+
+			if err != nil {
+				t.Fatalf("node %d is not ready: %v", i, err)
+			}
+		}
+	}
+
+	return nodes
+}
+
+// StartNode creates and starts a node with the given configuration,
+// used as is. The node is stopped and destroyed in t.Cleanup.
+func StartNode(t testing.TB, config libcodex.Config) *libcodex.CodexNode {
+	t.Helper()
+
+	node, err := libcodex.New(config)
+	if err != nil {
+		t.Fatalf("failed to create Codex node: %v", err)
+	}
+
+	// Registered before Start, so that the node is destroyed
+	// if the start fails.
+	t.Cleanup(func() {
+		if err := node.Close(); err != nil {
+			t.Errorf("failed to close Codex node: %v", err)
+		}
+	})
+
+	if err := node.Start(); err != nil {
+		t.Fatalf("failed to start Codex node: %v", err)
+	}
+
+	return node
+}
+
+// nodeConfig returns the configuration of a node, with a temporary
+// data dir and free ports.
+func nodeConfig(t testing.TB, base libcodex.Config) libcodex.Config {
+	t.Helper()
+
+	config := base
+	config.DataDir = t.TempDir()
+	config.ListenAddrs = []string{fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", FreeTCPPort(t))}
+	config.DiscoveryPort = FreeUDPPort(t)
+	config.BootstrapNodes = nil
+
+	if config.LogLevel == "" {
+		config.LogLevel = "ERROR"
+	}
+
+	if config.Nat == "" {
+		config.Nat = "extip:127.0.0.1"
+	}
+
+	return config
+}
+
+// connect connects the other nodes to the first one.
+func connect(t testing.TB, nodes []*libcodex.CodexNode) {
+	t.Helper()
+
+	info, err := nodes[0].Debug()
+	if err != nil {
+		t.Fatalf("failed to get the debug info of node 0: %v", err)
+	}
+
+	for i, node := range nodes[1:] {
+		if err := node.Connect(info.Id, info.Addrs); err != nil {
+			t.Fatalf("failed to connect node %d to node 0: %v", i+1, err)
+		}
+	}
+}
+
+// The ports are released before the nodes bind them, so the system
+// may return the same port twice. The ports already returned are
+// remembered to hand out each port once per process.
+var (
+	portsMu sync.Mutex
+	ports   = map[string]bool{}
+)
+
+// FreeTCPPort returns a TCP port free on the loopback interface.
+func FreeTCPPort(t testing.TB) int {
+	t.Helper()
+
+	return freePort(t, "tcp", func() (int, error) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		defer l.Close()
+
+		return l.Addr().(*net.TCPAddr).Port, nil
+	})
+}
+
+// FreeUDPPort returns a UDP port free on the loopback interface.
+func FreeUDPPort(t testing.TB) int {
+	t.Helper()
+
+	return freePort(t, "udp", func() (int, error) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	})
+}
+
+func freePort(t testing.TB, network string, listen func() (int, error)) int {
+	t.Helper()
+
+	portsMu.Lock()
+	defer portsMu.Unlock()
+
+	for attempt := 0; attempt < 100; attempt++ {
+		port, err := listen()
+		if err != nil {
+			t.Fatalf("failed to find a free %s port: %v", network, err)
+		}
+
+		key := fmt.Sprintf("%s/%d", network, port)
+		if !ports[key] {
+			ports[key] = true
+			return port
+		}
+	}
+
+	t.Fatalf("failed to find a free %s port: all the ports returned were already used", network)
+	return 0
+}
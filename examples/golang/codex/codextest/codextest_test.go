@@ -0,0 +1,872 @@
+package codextest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/libcodex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/memory"
+)
+
+func TestUploadOnOneNodeDownloadOnAnother(t *testing.T) {
+	nodes := StartNodes(t, 2, Options{})
+	data := bytes.Repeat([]byte("codex"), 50000)
+
+	cid, err := nodes[0].UploadReader(codex.UploadOptions{Filepath: "data.bin"}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := nodes[1].DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf, DatasetSizeAuto: true}); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("downloaded %d bytes, expected the %d bytes uploaded", buf.Len(), len(data))
+	}
+}
+
+func TestMemoryNodeComputesSameCids(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	// 1, 3 and 5 blocks: odd numbers of leaves on one and two layers
+	for _, size := range []int{12, 2545, 4595} {
+		data := bytes.Repeat([]byte("codex"), size/5)
+		options := codex.UploadOptions{Filename: "data.bin", Mimetype: "application/octet-stream", ChunkSize: 1024}
+
+		expected, err := node.UploadReader(options, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+
+		cid, err := memory.New().UploadReader(options, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("memory upload failed: %v", err)
+		}
+
+		if cid != expected {
+			t.Fatalf("expected cid %s for %d bytes, the memory node computed %s", expected, len(data), cid)
+		}
+	}
+}
+
+func TestFreePortsAreDistinct(t *testing.T) {
+	seen := map[int]bool{}
+
+	for i := 0; i < 20; i++ {
+		port := FreeTCPPort(t)
+		if seen[port] {
+			t.Fatalf("port %d returned twice", port)
+		}
+
+		seen[port] = true
+	}
+}
+
+func TestUploadWriter(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+	data := bytes.Repeat([]byte("codex"), 50000)
+	options := codex.UploadOptions{Filepath: "data.bin", ChunkSize: 1024}
+
+	expected, err := node.UploadReader(options, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	w, err := node.Create(options)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	// Writes which are not aligned on the chunk size
+	for i := 0; i < len(data); i += 777 {
+		if _, err := w.Write(data[i:min(i+777, len(data))]); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if w.Cid() != expected {
+		t.Fatalf("expected cid %s, got %s", expected, w.Cid())
+	}
+
+	aborted, err := node.Create(options)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if err := aborted.Abort(nil); err != nil {
+		t.Fatalf("abort failed: %v", err)
+	}
+
+	if _, err := aborted.Write(data); !errors.Is(err, libcodex.ErrUploadAborted) {
+		t.Fatalf("expected ErrUploadAborted, got %v", err)
+	}
+}
+
+func TestUploadReaderPartialReads(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+	data := bytes.Repeat([]byte("codex"), 1000)
+
+	readers := map[string]func() io.Reader{
+		"data with EOF": func() io.Reader {
+			return iotest.DataErrReader(bytes.NewReader(data))
+		},
+		"one byte": func() io.Reader {
+			return iotest.OneByteReader(bytes.NewReader(data))
+		},
+		"one byte with EOF": func() io.Reader {
+			return iotest.DataErrReader(iotest.OneByteReader(bytes.NewReader(data)))
+		},
+	}
+
+	for name, reader := range readers {
+		for _, concurrency := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s/concurrency %d", name, concurrency), func(t *testing.T) {
+				options := codex.UploadOptions{Filepath: "data.bin", ChunkSize: 1024, Concurrency: concurrency}
+
+				cid, err := node.UploadReader(options, reader())
+				if err != nil {
+					t.Fatalf("upload failed: %v", err)
+				}
+
+				var buf bytes.Buffer
+				if err := node.DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf, Local: true}); err != nil {
+					t.Fatalf("download failed: %v", err)
+				}
+
+				if !bytes.Equal(buf.Bytes(), data) {
+					t.Fatalf("downloaded %d bytes, expected the %d bytes uploaded", buf.Len(), len(data))
+				}
+			})
+		}
+	}
+}
+
+func TestUploadWriterSniffsMimetype(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+	html := []byte("<!DOCTYPE html><html><body>codex</body></html>")
+
+	percents := []float64{}
+	w, err := node.Create(codex.UploadOptions{
+		Filepath:  "page",
+		ChunkSize: 16,
+		OnProgress: func(read, total int, percent float64, err error) {
+			percents = append(percents, percent)
+		},
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if _, err := w.Write(html); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	manifest, err := node.DownloadManifest(w.Cid())
+	if err != nil {
+		t.Fatalf("failed to get the manifest: %v", err)
+	}
+
+	if manifest.Mimetype != "text/html" {
+		t.Fatalf("expected text/html, got %s", manifest.Mimetype)
+	}
+
+	for _, percent := range percents {
+		if percent != codex.PercentUnknown {
+			t.Fatalf("expected an unknown percent, got %v", percents)
+		}
+	}
+}
+
+func TestUploadFileResult(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+	data := bytes.Repeat([]byte("codex"), 5000)
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	var percent float64
+	result, err := node.UploadFileResult(codex.UploadOptions{
+		Filepath:  path,
+		ChunkSize: 1024,
+		OnProgress: func(read, total int, p float64, err error) {
+			percent = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if result.Sha256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("expected sha256 %x, got %s", sum, result.Sha256)
+	}
+
+	if result.Bytes != int64(len(data)) || result.Blocks != 25 || result.Filename != "data.bin" || percent != 100 {
+		t.Fatalf("unexpected result %+v, progress %v%%", result, percent)
+	}
+
+	cid, err := node.UploadReader(codex.UploadOptions{Filepath: "data.bin", ChunkSize: 1024}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if result.Cid != cid {
+		t.Fatalf("expected cid %s, got %s", cid, result.Cid)
+	}
+}
+
+func TestProgressCallbackCallsNode(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+	data := bytes.Repeat([]byte("codex"), 20000)
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		last := 0
+		_, err := node.UploadFile(codex.UploadOptions{
+			Filepath:  path,
+			ChunkSize: 1024,
+			// The Codex node must not wait for the callback once the
+			// buffer is full, the callback waits for the node
+			Progress: codex.ProgressOptions{Buffer: 1},
+			OnProgress: func(read, total int, percent float64, err error) {
+				node.Space()
+				last = total
+			},
+		})
+
+		if err == nil && last != len(data) {
+			err = fmt.Errorf("expected a total of %d bytes, got %d", len(data), last)
+		}
+
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+	case <-time.After(time.Minute):
+		t.Fatalf("the upload is stalled by its progress callback")
+	}
+}
+
+func TestUploadFileCancelled(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("codex"), 10_000_000), 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := node.UploadFileContext(ctx, codex.UploadOptions{Filepath: path, ChunkSize: 1024})
+	if !errors.Is(err, libcodex.ErrUploadCancelled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrUploadCancelled and context.Canceled, got %v", err)
+	}
+}
+
+func TestUploadTTLAndSetExpiry(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	data := bytes.Repeat([]byte("codex"), 100_000)
+	before := time.Now()
+
+	cid, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024, TTL: time.Hour}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	expiry, err := node.Expiry(cid)
+	if err != nil {
+		t.Fatalf("failed to get the expiry: %v", err)
+	}
+
+	if expiry.Before(before.Add(time.Hour).Truncate(time.Second)) || expiry.After(time.Now().Add(time.Hour+time.Second)) {
+		t.Fatalf("expected the expiry to be in one hour, got %v", expiry)
+	}
+
+	// Sooner than the current expiry
+	soon := time.Now().Add(time.Minute).Truncate(time.Second)
+	if err := node.SetExpiry(cid, soon); err != nil {
+		t.Fatalf("failed to set the expiry: %v", err)
+	}
+
+	expiry, err = node.Expiry(cid)
+	if err != nil {
+		t.Fatalf("failed to get the expiry: %v", err)
+	}
+
+	if !expiry.Equal(soon) {
+		t.Fatalf("expected the expiry %v, got %v", soon, expiry)
+	}
+}
+
+func TestManifestsPages(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	uploaded := map[string]bool{}
+	for i, name := range []string{"a.txt", "b.txt", "report-1.json", "report-2.json", "report-3.json"} {
+		cid, err := node.UploadReader(codex.UploadOptions{Filepath: name}, bytes.NewReader(bytes.Repeat([]byte{byte(i)}, 1000)))
+		if err != nil {
+			t.Fatalf("failed to upload %s: %v", name, err)
+		}
+
+		uploaded[cid] = true
+	}
+
+	listed := map[string]bool{}
+	for entry, err := range node.Manifests(context.Background(), libcodex.ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("failed to list: %v", err)
+		}
+
+		if entry.Manifest.Cid != entry.Cid {
+			t.Fatalf("expected the manifest cid to be set, got %+v", entry)
+		}
+
+		listed[entry.Cid] = true
+	}
+
+	if len(listed) != len(uploaded) {
+		t.Fatalf("expected %d manifests, got %d", len(uploaded), len(listed))
+	}
+
+	reports := 0
+	for entry, err := range node.Manifests(context.Background(), libcodex.ListOptions{Limit: 2, FilenamePrefix: "report-", Mimetype: "application/json"}) {
+		if err != nil {
+			t.Fatalf("failed to list: %v", err)
+		}
+
+		if !strings.HasPrefix(entry.Manifest.Filename, "report-") {
+			t.Fatalf("unexpected manifest %+v", entry.Manifest)
+		}
+
+		reports++
+	}
+
+	if reports != 3 {
+		t.Fatalf("expected 3 reports, got %d", reports)
+	}
+}
+
+func TestListPageCursorAfterDelete(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	for i := range 4 {
+		if _, err := node.UploadReader(codex.UploadOptions{}, bytes.NewReader(bytes.Repeat([]byte{byte(i)}, 1000))); err != nil {
+			t.Fatalf("failed to upload: %v", err)
+		}
+	}
+
+	first, err := node.ListPage(libcodex.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	if len(first.Items) != 2 || first.Next == "" {
+		t.Fatalf("expected a full first page, got %+v", first)
+	}
+
+	// Deleting a listed manifest does not shift the next page
+	if err := node.Delete(first.Items[0].Cid); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	second, err := node.ListPage(libcodex.ListOptions{Limit: 2, Cursor: first.Next})
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	if len(second.Items) != 2 || second.Next != "" {
+		t.Fatalf("expected a full last page, got %+v", second)
+	}
+
+	for _, entry := range second.Items {
+		for _, listed := range first.Items {
+			if entry.Cid == listed.Cid {
+				t.Fatalf("manifest %s listed twice", entry.Cid)
+			}
+		}
+	}
+}
+
+func TestAnnounceAndFindProviders(t *testing.T) {
+	nodes := StartNodes(t, 2, Options{})
+
+	cid, err := nodes[0].UploadReader(codex.UploadOptions{}, bytes.NewReader([]byte("provided content")))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	if err := nodes[0].Announce(cid); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+
+	peerId, err := nodes[0].PeerId()
+	if err != nil {
+		t.Fatalf("failed to get the peer id: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	providers, err := nodes[1].FindProviders(ctx, cid)
+	if err != nil {
+		t.Fatalf("failed to find the providers: %v", err)
+	}
+
+	if len(providers) != 1 || providers[0].PeerId != peerId || len(providers[0].Addresses) == 0 {
+		t.Fatalf("expected node 0 to be the provider, got %+v", providers)
+	}
+
+	if err := nodes[1].Announce(cid); err == nil {
+		t.Fatalf("expected announcing content not stored to fail")
+	}
+}
+
+func TestUploadWithoutAnnounce(t *testing.T) {
+	nodes := StartNodes(t, 2, Options{})
+
+	announce := false
+	cid, err := nodes[0].UploadReader(codex.UploadOptions{Announce: &announce}, bytes.NewReader([]byte("private content")))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	providers, err := nodes[1].FindProviders(ctx, cid)
+	if err != nil {
+		t.Fatalf("failed to find the providers: %v", err)
+	}
+
+	if len(providers) != 0 {
+		t.Fatalf("expected the content not to be announced, got %+v", providers)
+	}
+
+	if err := nodes[0].Announce(cid); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+
+	providers, err = nodes[1].FindProviders(ctx, cid)
+	if err != nil {
+		t.Fatalf("failed to find the providers: %v", err)
+	}
+
+	if len(providers) != 1 {
+		t.Fatalf("expected node 0 to be the provider once announced, got %+v", providers)
+	}
+}
+
+func TestDenyPeer(t *testing.T) {
+	nodes := []*libcodex.CodexNode{
+		StartNode(t, nodeConfig(t, libcodex.Config{})),
+		StartNode(t, nodeConfig(t, libcodex.Config{})),
+	}
+
+	rejected := make(chan libcodex.PeerRejected, 1)
+	nodes[0].OnEvent(func(event libcodex.Event) {
+		if event.Type != libcodex.EventPeerRejected {
+			return
+		}
+
+		var data libcodex.PeerRejected
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			select {
+			case rejected <- data:
+			default:
+			}
+		}
+	})
+
+	info, err := nodes[0].Debug()
+	if err != nil {
+		t.Fatalf("failed to get the debug info of node 0: %v", err)
+	}
+
+	peerId, err := nodes[1].PeerId()
+	if err != nil {
+		t.Fatalf("failed to get the peer id: %v", err)
+	}
+
+	if err := nodes[0].DenyPeer(peerId); err != nil {
+		t.Fatalf("failed to deny the peer: %v", err)
+	}
+
+	if err := nodes[0].Connect(peerId, nil); err == nil {
+		t.Fatalf("expected connecting to a denied peer to fail")
+	}
+
+	// The connection is closed once it is established
+	nodes[1].Connect(info.Id, info.Addrs)
+
+	select {
+	case data := <-rejected:
+		if data.PeerId != peerId || data.Direction != "inbound" {
+			t.Fatalf("expected the inbound connection of %s to be rejected, got %+v", peerId, data)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the connection of the denied peer was not rejected")
+	}
+
+	if err := nodes[0].AllowPeer(peerId); err != nil {
+		t.Fatalf("failed to allow the peer: %v", err)
+	}
+
+	if err := nodes[1].Connect(info.Id, info.Addrs); err != nil {
+		t.Fatalf("failed to connect once allowed: %v", err)
+	}
+
+	if err := nodes[0].Disconnect(peerId); err != nil {
+		t.Fatalf("failed to disconnect the peer: %v", err)
+	}
+}
+
+func TestPeersStatistics(t *testing.T) {
+	nodes := StartNodes(t, 2, Options{Mode: ModeConnect})
+	data := bytes.Repeat([]byte("codex"), 10000)
+
+	cid, err := nodes[0].UploadReader(codex.UploadOptions{}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := nodes[1].DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf}); err != nil {
+		t.Fatalf("failed to download: %v", err)
+	}
+
+	id0, err := nodes[0].PeerId()
+	if err != nil {
+		t.Fatalf("failed to get the peer id: %v", err)
+	}
+
+	peers, err := nodes[1].Peers()
+	if err != nil {
+		t.Fatalf("failed to get the peers: %v", err)
+	}
+
+	if len(peers) != 1 || peers[0].PeerId != id0 || peers[0].Direction != "outbound" {
+		t.Fatalf("expected node 0 as outbound peer, got %+v", peers)
+	}
+
+	if peers[0].BlocksReceived == 0 || peers[0].BytesReceived < int64(len(data)) || peers[0].Agent == "" {
+		t.Fatalf("expected the blocks received from node 0, got %+v", peers[0])
+	}
+
+	peers, err = nodes[0].Peers()
+	if err != nil {
+		t.Fatalf("failed to get the peers: %v", err)
+	}
+
+	if len(peers) != 1 || peers[0].Direction != "inbound" || peers[0].BlocksSent == 0 || peers[0].BytesSent < int64(len(data)) {
+		t.Fatalf("expected the blocks sent to node 1, got %+v", peers)
+	}
+}
+
+func TestPeerBookReconnects(t *testing.T) {
+	node0 := StartNode(t, nodeConfig(t, libcodex.Config{}))
+	config := nodeConfig(t, libcodex.Config{})
+	node1 := StartNode(t, config)
+
+	connect(t, []*libcodex.CodexNode{node0, node1})
+
+	id0, err := node0.PeerId()
+	if err != nil {
+		t.Fatalf("failed to get the peer id: %v", err)
+	}
+
+	book := node1.PeerBook()
+	if len(book) != 1 || book[0].PeerId != id0 || len(book[0].Addresses) == 0 {
+		t.Fatalf("expected node 0 in the peer book, got %+v", book)
+	}
+
+	// The peer book is read from the data dir after a restart
+	if err := node1.Close(); err != nil {
+		t.Fatalf("failed to close node 1: %v", err)
+	}
+
+	node1 = StartNode(t, config)
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		peers, err := node1.Peers()
+		if err != nil {
+			t.Fatalf("failed to get the peers: %v", err)
+		}
+
+		if len(peers) == 1 && peers[0].PeerId == id0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("node 1 did not reconnect to node 0, peers %+v", peers)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func TestDeniedPeerLeavesPeerBook(t *testing.T) {
+	node0 := StartNode(t, nodeConfig(t, libcodex.Config{}))
+	node1 := StartNode(t, nodeConfig(t, libcodex.Config{}))
+
+	connect(t, []*libcodex.CodexNode{node0, node1})
+
+	id0, err := node0.PeerId()
+	if err != nil {
+		t.Fatalf("failed to get the peer id: %v", err)
+	}
+
+	// Listed by Peers, the peer keeps its Connect success
+	if _, err := node1.Peers(); err != nil {
+		t.Fatalf("failed to get the peers: %v", err)
+	}
+
+	book := node1.PeerBook()
+	if len(book) != 1 || book[0].LastSuccess.IsZero() || book[0].LastSeen.IsZero() {
+		t.Fatalf("expected node 0 in the peer book, got %+v", book)
+	}
+
+	if err := node1.DenyPeer(id0); err != nil {
+		t.Fatalf("failed to deny: %v", err)
+	}
+
+	if book := node1.PeerBook(); len(book) > 0 {
+		t.Fatalf("expected the denied peer to leave the peer book, got %+v", book)
+	}
+}
+
+func TestCloseWaitsForCallsInFlight(t *testing.T) {
+	node := StartNodes(t, 1, Options{})[0]
+
+	cid, err := node.UploadReader(codex.UploadOptions{}, strings.NewReader("in flight"))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	errs := make(chan error, 8)
+	for range cap(errs) {
+		go func() {
+			for {
+				if _, err := node.Exists(cid); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := node.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	// The calls end with ErrInvalidState once Close starts, the calls
+	// already in flight complete before the node is destroyed
+	for range cap(errs) {
+		if err := <-errs; !errors.Is(err, libcodex.ErrInvalidState) {
+			t.Fatalf("expected ErrInvalidState, got %v", err)
+		}
+	}
+}
+
+// interruptedUpload uploads the first half of data to a session of a node
+// created with config, then closes the node. It returns the session id.
+func interruptedUpload(t *testing.T, config libcodex.Config, data []byte, options codex.UploadOptions) string {
+	t.Helper()
+
+	node, err := libcodex.New(config)
+	if err != nil {
+		t.Fatalf("failed to create Codex node: %v", err)
+	}
+
+	if err := node.Start(); err != nil {
+		t.Fatalf("failed to start Codex node: %v", err)
+	}
+
+	sessionId, err := node.UploadInit(&options)
+	if err != nil {
+		t.Fatalf("upload init failed: %v", err)
+	}
+
+	for i := 0; i < len(data)/2; i += 1024 {
+		if err := node.UploadChunk(sessionId, data[i:i+1024]); err != nil {
+			t.Fatalf("upload chunk failed: %v", err)
+		}
+	}
+
+	if err := node.Close(); err != nil {
+		t.Fatalf("failed to close Codex node: %v", err)
+	}
+
+	return sessionId
+}
+
+func TestResumeUploadAfterRestart(t *testing.T) {
+	config := nodeConfig(t, libcodex.Config{})
+	data := bytes.Repeat([]byte("codex"), 50000)
+	options := codex.UploadOptions{Filepath: "data.bin", ChunkSize: 1024}
+
+	sessionId := interruptedUpload(t, config, data, options)
+	restarted := StartNode(t, config)
+
+	resumedBytes := 0
+	resumeOptions := codex.UploadOptions{
+		Concurrency: 4,
+		OnProgress: func(read, total int, percent float64, err error) {
+			resumedBytes = total
+		},
+	}
+
+	cid, err := restarted.ResumeUpload(sessionId, resumeOptions, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if resumedBytes == 0 || resumedBytes >= len(data) {
+		t.Fatalf("expected the progress of the rest of the upload, got %d bytes", resumedBytes)
+	}
+
+	expected, err := restarted.UploadReader(options, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if cid != expected {
+		t.Fatalf("expected cid %s, got %s", expected, cid)
+	}
+
+	if _, err := restarted.UploadResume(sessionId); err == nil {
+		t.Fatalf("expected the finalized session not to be resumable")
+	}
+}
+
+func TestResumedSessionRejectsFileUpload(t *testing.T) {
+	config := nodeConfig(t, libcodex.Config{})
+	data := bytes.Repeat([]byte("codex"), 50000)
+	options := codex.UploadOptions{Filepath: "data.bin", ChunkSize: 1024}
+
+	sessionId := interruptedUpload(t, config, data, options)
+	restarted := StartNode(t, config)
+
+	resumed, err := restarted.UploadResume(sessionId)
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	if _, err := restarted.UploadFileSession(context.Background(), sessionId, codex.UploadOptions{Filepath: path}); err == nil {
+		t.Fatalf("expected the resumed session to reject the file upload")
+	}
+
+	// The session and its stored blocks are kept
+	cid, err := restarted.UploadReaderSession(sessionId, options, bytes.NewReader(data[resumed.Bytes:]))
+	if err != nil {
+		t.Fatalf("failed to upload the rest: %v", err)
+	}
+
+	expected, err := restarted.UploadReader(options, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if cid != expected {
+		t.Fatalf("expected cid %s, got %s", expected, cid)
+	}
+}
+
+func TestIdleUploadSessionExpires(t *testing.T) {
+	config := libcodex.Config{UploadSessionIdleTimeout: libcodex.Duration(time.Second)}
+	node := StartNodes(t, 1, Options{Config: config})[0]
+
+	expired := make(chan libcodex.UploadSessionExpired, 1)
+	node.OnEvent(func(event libcodex.Event) {
+		if event.Type != libcodex.EventUploadSessionExpired {
+			return
+		}
+
+		var data libcodex.UploadSessionExpired
+		if err := json.Unmarshal(event.Data, &data); err == nil {
+			select {
+			case expired <- data:
+			default:
+			}
+		}
+	})
+
+	sessionId, err := node.UploadInit(&codex.UploadOptions{Filepath: "data.bin"})
+	if err != nil {
+		t.Fatalf("upload init failed: %v", err)
+	}
+
+	if err := node.UploadChunk(sessionId, []byte("codex")); err != nil {
+		t.Fatalf("upload chunk failed: %v", err)
+	}
+
+	sessions, err := node.ListUploadSessions()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].SessionId != sessionId || sessions[0].Bytes != 5 {
+		t.Fatalf("expected the session %s with 5 bytes, got %+v", sessionId, sessions)
+	}
+
+	select {
+	case data := <-expired:
+		if data.SessionId != sessionId || data.Reason != "idle" {
+			t.Fatalf("expected the idle session %s to expire, got %+v", sessionId, data)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the idle session did not expire")
+	}
+
+	sessions, err = node.ListUploadSessions()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	if len(sessions) != 0 {
+		t.Fatalf("expected no session, got %+v", sessions)
+	}
+}
@@ -0,0 +1,229 @@
+package codextest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/libcodex"
+)
+
+// runNode creates, starts, uploads on, downloads from and destroys a node.
+func runNode(config libcodex.Config, data []byte) error {
+	node, err := libcodex.New(config)
+	if err != nil {
+		return fmt.Errorf("failed to create the node: %w", err)
+	}
+
+	err = func() error {
+		if err := node.Start(); err != nil {
+			return fmt.Errorf("failed to start the node: %w", err)
+		}
+
+		cid, err := node.UploadReader(codex.UploadOptions{Filepath: "data.bin"}, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to upload: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := node.DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf, Local: true}); err != nil {
+			return fmt.Errorf("failed to download: %w", err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), data) {
+			return fmt.Errorf("downloaded %d bytes, expected %d", buf.Len(), len(data))
+		}
+
+		return nil
+	}()
+
+	return errors.Join(err, node.Close())
+}
+
+func openFiles() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	return len(entries)
+}
+
+func TestManyNodesConcurrently(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the stress test in short mode")
+	}
+
+	const (
+		nodes  = 8
+		rounds = 3
+	)
+
+	var files []int
+
+	for round := 0; round < rounds; round++ {
+		configs := make([]libcodex.Config, nodes)
+		for i := range configs {
+			configs[i] = nodeConfig(t, libcodex.Config{})
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, nodes)
+
+		for i, config := range configs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				// Each node uploads its own content
+				data := bytes.Repeat([]byte(fmt.Sprintf("node %d round %d ", i, round)), 10000)
+				errs[i] = runNode(config, data)
+			}()
+		}
+
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("round %d, node %d: %v", round, i, err)
+			}
+		}
+
+		files = append(files, openFiles())
+	}
+
+	// The first round may open files kept by the runtime,
+	// the next ones must release everything they open.
+	if files[0] >= 0 && files[rounds-1] > files[1]+nodes {
+		t.Fatalf("open files grew from %d to %d: %v", files[1], files[rounds-1], files)
+	}
+}
+
+func TestEventsStayOnTheirNode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the stress test in short mode")
+	}
+
+	const count = 4
+
+	nodes := make([]*libcodex.CodexNode, count)
+	for i := range nodes {
+		nodes[i] = StartNode(t, nodeConfig(t, libcodex.Config{}))
+	}
+
+	ids := make([]string, count)
+	infos := make([]codex.DebugInfo, count)
+	for i, node := range nodes {
+		info, err := node.Debug()
+		if err != nil {
+			t.Fatalf("failed to get the debug info of node %d: %v", i, err)
+		}
+
+		ids[i], infos[i] = info.Id, info
+	}
+
+	// Node i denies node i+1, so it only emits events for this peer
+	var mu sync.Mutex
+	rejected := make([][]string, count)
+	for i, node := range nodes {
+		node.OnEvent(func(event libcodex.Event) {
+			var data libcodex.PeerRejected
+			if event.Type != libcodex.EventPeerRejected || json.Unmarshal(event.Data, &data) != nil {
+				return
+			}
+
+			mu.Lock()
+			rejected[i] = append(rejected[i], data.PeerId)
+			mu.Unlock()
+		})
+
+		if err := node.DenyPeer(ids[(i+1)%count]); err != nil {
+			t.Fatalf("failed to deny on node %d: %v", i, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, count)
+
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Rejected by node i-1
+			previous := (i + count - 1) % count
+			node.Connect(infos[previous].Id, infos[previous].Addrs)
+
+			// Each node has a size of its own, the progress of another
+			// node would go past it
+			data := bytes.Repeat([]byte{byte(i)}, (i+1)*100_000)
+			cid, err := node.UploadReader(codex.UploadOptions{}, bytes.NewReader(data))
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to upload: %w", err)
+				return
+			}
+
+			received := 0
+			err = node.DownloadStream(cid, codex.DownloadStreamOptions{
+				Local: true,
+				OnProgress: func(read, total int, _ float64, err error) {
+					received += read
+					if total > len(data) || received != total {
+						errs[i] = fmt.Errorf("unexpected progress %d / %d of %d bytes", received, total, len(data))
+					}
+				},
+			})
+
+			if err != nil {
+				errs[i] = errors.Join(errs[i], fmt.Errorf("failed to download: %w", err))
+			} else if errs[i] == nil && received != len(data) {
+				errs[i] = fmt.Errorf("received %d bytes, expected %d", received, len(data))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for i := range nodes {
+		for {
+			mu.Lock()
+			events := slices.Clone(rejected[i])
+			mu.Unlock()
+
+			if len(events) > 0 {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("node %d did not reject node %d", i, (i+1)%count)
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, events := range rejected {
+		for _, peerId := range events {
+			if peerId != ids[(i+1)%count] {
+				t.Fatalf("node %d received the event of peer %s, denied by another node", i, peerId)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package codextest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// BenchmarkUploadReader compares the sequential upload (concurrency 1)
+// with the pipelined upload for several chunk sizes:
+//
+//	go test -run '^$' -bench UploadReader ./codex/codextest/
+func BenchmarkUploadReader(b *testing.B) {
+	node := StartNodes(b, 1, Options{})[0]
+
+	data := make([]byte, 16*1024*1024)
+	rand.Read(data)
+
+	for _, chunkSize := range []int{16 * 1024, 64 * 1024, 256 * 1024, 1024 * 1024} {
+		for _, concurrency := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("chunk=%dKiB/concurrency=%d", chunkSize/1024, concurrency), func(b *testing.B) {
+				options := codex.UploadOptions{
+					Filepath:    "bench.bin",
+					ChunkSize:   codex.ChunkSize(chunkSize),
+					Concurrency: concurrency,
+				}
+
+				b.SetBytes(int64(len(data)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					// Change the content to avoid uploading blocks already stored
+					data[0], data[len(data)-1] = byte(i), byte(i>>8)
+
+					if _, err := node.UploadReader(options, bytes.NewReader(data)); err != nil {
+						b.Fatalf("upload failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
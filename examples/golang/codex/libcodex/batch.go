@@ -0,0 +1,41 @@
+package libcodex
+
+import (
+	"context"
+	"iter"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// ExistsMany checks if each CID is available in the local store,
+// with up to options.Concurrency calls in flight, see codex.ExistsMany.
+func (node *CodexNode) ExistsMany(ctx context.Context, cids []string, options codex.BatchOptions) (map[string]bool, error) {
+	return codex.ExistsMany(ctx, node, cids, options)
+}
+
+// ExistsStream yields the results of ExistsMany as they complete.
+func (node *CodexNode) ExistsStream(ctx context.Context, cids []string, options codex.BatchOptions) iter.Seq[codex.BatchResult[bool]] {
+	return codex.ExistsStream(ctx, node, cids, options)
+}
+
+// DeleteMany deletes each CID from the node, with up to
+// options.Concurrency calls in flight, see codex.DeleteMany.
+func (node *CodexNode) DeleteMany(ctx context.Context, cids []string, options codex.BatchOptions) (map[string]error, error) {
+	return codex.DeleteMany(ctx, node, cids, options)
+}
+
+// DeleteStream yields the results of DeleteMany as they complete.
+func (node *CodexNode) DeleteStream(ctx context.Context, cids []string, options codex.BatchOptions) iter.Seq[codex.BatchResult[struct{}]] {
+	return codex.DeleteStream(ctx, node, cids, options)
+}
+
+// FetchMany starts the download of each CID to the node, with up to
+// options.Concurrency calls in flight, see codex.FetchMany.
+func (node *CodexNode) FetchMany(ctx context.Context, cids []string, options codex.BatchOptions) (map[string]codex.Manifest, error) {
+	return codex.FetchMany(ctx, node, cids, options)
+}
+
+// FetchStream yields the results of FetchMany as they complete.
+func (node *CodexNode) FetchStream(ctx context.Context, cids []string, options codex.BatchOptions) iter.Seq[codex.BatchResult[codex.Manifest]] {
+	return codex.FetchStream(ctx, node, cids, options)
+}
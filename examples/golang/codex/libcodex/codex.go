@@ -0,0 +1,1875 @@
+// Package libcodex runs a Codex node inside the current process,
+// through the C bindings exposed by libcodex.so.
+package libcodex
+
+/*
+   	#cgo LDFLAGS: -L${SRCDIR}/../../../../build/ -lcodex
+	#cgo LDFLAGS: -L${SRCDIR}/../../../../ -Wl,-rpath,${SRCDIR}/../../../../build/
+
+	#include <stdbool.h>
+   	#include <stdlib.h>
+   	#include "../../../../library/libcodex.h"
+
+   // resp must be set != NULL in case interest on retrieving data from the callback
+   void callback(int ret, char* msg, size_t len, void* resp);
+
+   static void* cGoCodexNew(const char* configJson, void* resp) {
+       void* ret = codex_new(configJson, (CodexCallback) callback, resp);
+       return ret;
+   }
+
+   static int cGoCodexStart(void* codexCtx, void* resp) {
+       return codex_start(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexStop(void* codexCtx, void* resp) {
+       return codex_stop(codexCtx, (CodexCallback) callback, resp);
+   }
+
+	static int cGoCodexClose(void* codexCtx, void* resp) {
+		return codex_close(codexCtx, (CodexCallback) callback, resp);
+	}
+
+   static int cGoCodexDestroy(void* codexCtx, void* resp) {
+       return codex_destroy(codexCtx, (CodexCallback) callback, resp);
+   }
+
+    static int cGoCodexVersion(void* codexCtx, void* resp) {
+       return codex_version(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexRevision(void* codexCtx, void* resp) {
+       return codex_revision(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexRepo(void* codexCtx, void* resp) {
+       return codex_repo(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexSpr(void* codexCtx, void* resp) {
+       return codex_spr(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexPeerId(void* codexCtx, void* resp) {
+       return codex_peer_id(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadInit(void* codexCtx, char* filepath, size_t chunkSize, char* filename, char* mimetype, int64_t ttl, bool announce, void* resp) {
+      return codex_upload_init(codexCtx, filepath, chunkSize, filename, mimetype, ttl, announce, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadChunk(void* codexCtx, char* sessionId, const uint8_t* chunk, size_t len, void* resp) {
+      return codex_upload_chunk(codexCtx, sessionId, chunk, len, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadFinalize(void* codexCtx, char* sessionId, void* resp) {
+      return codex_upload_finalize(codexCtx, sessionId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadCancel(void* codexCtx, char* sessionId, void* resp) {
+      return codex_upload_cancel(codexCtx, sessionId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadFile(void* codexCtx, char* sessionId, void* resp) {
+      return codex_upload_file(codexCtx, sessionId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadFileResult(void* codexCtx, char* sessionId, void* resp) {
+      return codex_upload_file_result(codexCtx, sessionId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadResume(void* codexCtx, char* sessionId, void* resp) {
+      return codex_upload_resume(codexCtx, sessionId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexUploadList(void* codexCtx, void* resp) {
+      return codex_upload_list(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexLogLevel(void* codexCtx, char* logLevel, void* resp) {
+       return codex_log_level(codexCtx, logLevel, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDebug(void* codexCtx, void* resp) {
+       return codex_debug(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexConnect(void* codexCtx, char* peerId, const char** peerAddresses, size_t peerAddressesSize, void* resp) {
+       return codex_connect(codexCtx, peerId, peerAddresses, peerAddressesSize, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexFindProviders(void* codexCtx, char* cid, void* resp) {
+       return codex_find_providers(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexAnnounce(void* codexCtx, char* cid, void* resp) {
+       return codex_announce(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexPeers(void* codexCtx, void* resp) {
+       return codex_peers(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexAllowPeer(void* codexCtx, char* peerId, void* resp) {
+       return codex_allow_peer(codexCtx, peerId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDenyPeer(void* codexCtx, char* peerId, void* resp) {
+       return codex_deny_peer(codexCtx, peerId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDisconnect(void* codexCtx, char* peerId, void* resp) {
+       return codex_disconnect(codexCtx, peerId, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDownloadInit(void* codexCtx, char* cid, size_t chunkSize, bool local, void* resp) {
+      return codex_download_init(codexCtx, cid, chunkSize, local, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDownloadChunk(void* codexCtx, char* cid, void* resp) {
+      return codex_download_chunk(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDownloadStream(void* codexCtx, char* cid, size_t chunkSize, bool local, const char* filepath, void* resp) {
+      return codex_download_stream(codexCtx, cid, chunkSize, local, filepath, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDownloadCancel(void* codexCtx, char* cid, void* resp) {
+      return codex_download_cancel(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDownloadManifest(void* codexCtx, char* cid, void* resp) {
+      return codex_download_manifest(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexList(void* codexCtx, void* resp) {
+      return codex_storage_list(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexListPage(void* codexCtx, char* cursor, size_t limit, char* mimetype, char* filenamePrefix, void* resp) {
+      return codex_storage_list_page(codexCtx, cursor, limit, mimetype, filenamePrefix, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexSpace(void* codexCtx, void* resp) {
+      return codex_storage_space(codexCtx, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexDelete(void* codexCtx, char* cid, void* resp) {
+      return codex_storage_delete(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexFetch(void* codexCtx, char* cid, void* resp) {
+      return codex_storage_fetch(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexExists(void* codexCtx, char* cid, void* resp) {
+      return codex_storage_exists(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexSetExpiry(void* codexCtx, char* cid, int64_t expiry, void* resp) {
+      return codex_storage_set_expiry(codexCtx, cid, expiry, (CodexCallback) callback, resp);
+   }
+
+   static int cGoCodexExpiry(void* codexCtx, char* cid, void* resp) {
+      return codex_storage_expiry(codexCtx, cid, (CodexCallback) callback, resp);
+   }
+*/
+import "C"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/cgo"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// CodexNode implements the common node interface.
+var _ codex.Node = (*CodexNode)(nil)
+
+var _ io.Closer = (*CodexNode)(nil)
+
+type LogFormat string
+
+const (
+	LogFormatAuto     LogFormat = "auto"
+	LogFormatColors   LogFormat = "colors"
+	LogFormatNoColors LogFormat = "nocolors"
+	LogFormatJSON     LogFormat = "json"
+)
+
+// Duration is a duration setting of the Codex node,
+// sent in seconds.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%ds", int64(time.Duration(d)/time.Second)))
+}
+
+type RepoKind string
+
+const (
+	FS      RepoKind = "fs"
+	SQLite  RepoKind = "sqlite"
+	LevelDb RepoKind = "leveldb"
+)
+
+type Config struct {
+	// Default: INFO
+	LogLevel string `json:"log-level,omitempty"`
+
+	// Specifies what kind of logs should be written to stdout
+	// Default: auto
+	LogFormat LogFormat `json:"log-format,omitempty"`
+
+	// Enable the metrics server
+	// Default: false
+	MetricsEnabled bool `json:"metrics,omitempty"`
+
+	// Listening address of the metrics server
+	// Default: 127.0.0.1
+	MetricsAddress string `json:"metrics-address,omitempty"`
+
+	// Listening HTTP port of the metrics server
+	// Default: 8008
+	MetricsPort int `json:"metrics-port,omitempty"`
+
+	// The directory where codex will store configuration and data
+	// Default:
+	// $HOME\AppData\Roaming\Codex on Windows
+	// $HOME/Library/Application Support/Codex on macOS
+	// $HOME/.cache/codex on Linux
+	DataDir string `json:"data-dir,omitempty"`
+
+	// Multi Addresses to listen on
+	// Default: ["/ip4/0.0.0.0/tcp/0"]
+	ListenAddrs []string `json:"listen-addrs,omitempty"`
+
+	// Specify method to use for determining public address.
+	// Must be one of: any, none, upnp, pmp, extip:<IP>
+	// Default: any
+	Nat string `json:"nat,omitempty"`
+
+	// Discovery (UDP) port
+	// Default: 8090
+	DiscoveryPort int `json:"disc-port,omitempty"`
+
+	// Source of network (secp256k1) private key file path or name
+	// Default: "key"
+	NetPrivKeyFile string `json:"net-privkey,omitempty"`
+
+	// Specifies one or more bootstrap nodes to use when connecting to the network.
+	BootstrapNodes []string `json:"bootstrap-node,omitempty"`
+
+	// The maximum number of peers to connect to.
+	// Default: 160
+	MaxPeers int `json:"max-peers,omitempty"`
+
+	// Number of worker threads (\"0\" = use as many threads as there are CPU cores available)
+	// Default: 0
+	NumThreads int `json:"num-threads,omitempty"`
+
+	// Node agent string which is used as identifier in network
+	// Default: "Codex"
+	AgentString string `json:"agent-string,omitempty"`
+
+	// Backend for main repo store (fs, sqlite, leveldb)
+	// Default: fs
+	RepoKind RepoKind `json:"repo-kind,omitempty"`
+
+	// The size of the total storage quota dedicated to the node
+	// Default: 20 GiBs
+	StorageQuota int `json:"storage-quota,omitempty"`
+
+	// Default block timeout in seconds - 0 disables the ttl
+	// Default: 30 days
+	BlockTtl int `json:"block-ttl,omitempty"`
+
+	// Time interval in seconds - determines frequency of block
+	// maintenance cycle: how often blocks are checked for expiration and cleanup
+	// Default: 10 minutes
+	BlockMaintenanceInterval int `json:"block-mi,omitempty"`
+
+	// Number of blocks to check every maintenance cycle
+	// Default: 1000
+	BlockMaintenanceNumberOfBlocks int `json:"block-mn,omitempty"`
+
+	// Number of times to retry fetching a block before giving up
+	// Default: 3000
+	BlockRetries int `json:"block-retries,omitempty"`
+
+	// The size of the block cache, 0 disables the cache -
+	// might help on slow hardrives
+	// Default: 0
+	CacheSize int `json:"cache-size,omitempty"`
+
+	// Default: "" (no log file)
+	LogFile string `json:"log-file,omitempty"`
+
+	// Time after the last stored block after which an interrupted
+	// upload session expires and cannot be resumed anymore
+	// Default: 1 day
+	UploadSessionTtl Duration `json:"upload-session-ttl,omitempty"`
+
+	// Time without activity after which an upload session is cancelled
+	// by the node, which emits an EventUploadSessionExpired event
+	// Default: 1 hour
+	UploadSessionIdleTimeout Duration `json:"upload-session-idle-timeout,omitempty"`
+
+	// Do not announce the datasets to the network, except the ones
+	// published with Announce, and only serve blocks to PrivatePeers
+	// Default: false
+	PrivateMode bool `json:"private-mode,omitempty"`
+
+	// Peer IDs of the peers allowed to retrieve blocks in private mode
+	PrivatePeers []string `json:"private-peer,omitempty"`
+
+	// Peer IDs of the peers allowed to connect. When set, the connections
+	// of the other peers are closed once set up. See CodexNode.AllowPeer.
+	AllowedPeers []string `json:"allowed-peer,omitempty"`
+
+	// Peer IDs of the peers whose connections are closed once set up.
+	// See CodexNode.DenyPeer.
+	DeniedPeers []string `json:"denied-peer,omitempty"`
+
+	// Number of peers of the peer book reconnected in the background
+	// by Start, -1 disables the reconnection. See CodexNode.PeerBook.
+	// Default: DefaultReconnectPeers
+	ReconnectPeers int `json:"-"`
+}
+
+// CodexNode is a Codex node running in the libcodex thread.
+// It tracks its lifecycle state to reject the calls which would
+// be invalid for the Nim side, see State.
+//
+// The peers denied with Config.DeniedPeers, Config.AllowedPeers or
+// DenyPeer are not blocked before they connect: their connections are
+// set up, handshakes and identify included, then closed. They are
+// never served blocks.
+// A CodexNode must not be copied, use the pointer returned by New.
+type CodexNode struct {
+	ctx unsafe.Pointer
+
+	mu    sync.Mutex
+	state State
+
+	// Calls in flight, the transitions wait for them
+	calls int
+	idle  *sync.Cond
+
+	// Set by Shutdown to reject the new calls
+	draining bool
+
+	// Upload and download sessions in progress
+	sessions sessions
+
+	// Handle passed to the Codex context to route its events to the node
+	events  cgo.Handle
+	onEvent func(Event)
+
+	// Peers reconnected by Start, nil when the data dir is unknown
+	peerBook        *peerBook
+	denied          map[string]struct{}
+	reconnectPeers  int
+	reconnectCancel context.CancelFunc
+	reconnectWg     sync.WaitGroup
+}
+
+func toSizeT(c codex.ChunkSize) C.size_t {
+	return C.size_t(c.ValOrDefault())
+}
+
+// New creates a new Codex node with the provided configuration.
+// The node is not started automatically; you need to call CodexStart
+// to start it.
+// It returns a Codex node that can be used to interact
+// with the Codex network.
+func New(config Config) (*CodexNode, error) {
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	jsonConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cJsonConfig := C.CString(string(jsonConfig))
+	defer C.free(unsafe.Pointer(cJsonConfig))
+
+	ctx := C.cGoCodexNew(cJsonConfig, bridge.resp)
+
+	if _, err := bridge.wait(); err != nil {
+		return nil, err
+	}
+
+	node := &CodexNode{ctx: ctx, state: StateCreated, reconnectPeers: config.ReconnectPeers, denied: map[string]struct{}{}}
+	for _, peerId := range config.DeniedPeers {
+		node.denied[peerId] = struct{}{}
+	}
+
+	node.registerEvents()
+
+	if repo, err := node.Repo(); err == nil {
+		node.peerBook = loadPeerBook(filepath.Join(repo, PeerBookFile))
+	}
+
+	return node, nil
+}
+
+// Start starts the Codex node.
+// The node must be created or stopped. Once started, the node reconnects
+// in the background to the most recent peers of its peer book.
+func (node *CodexNode) Start() error {
+	previous, err := node.transition("start", StateStarting, StateCreated, StateStopped)
+	if err != nil {
+		return err
+	}
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexStart(node.ctx, bridge.resp) != C.RET_OK {
+		node.setState(previous)
+		return bridge.callError("cGoCodexStart")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		node.setState(previous)
+		return err
+	}
+
+	node.setState(StateRunning)
+	node.startReconnect()
+
+	return nil
+}
+
+// StartAsync is the asynchronous version of Start.
+func (node *CodexNode) StartAsync(onDone func(error)) {
+	go func() {
+		err := node.Start()
+		onDone(err)
+	}()
+}
+
+// Stop stops the Codex node.
+// The node must be running.
+func (node *CodexNode) Stop() error {
+	if _, err := node.transition("stop", StateStopping, StateRunning); err != nil {
+		return err
+	}
+
+	node.stopReconnect()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexStop(node.ctx, bridge.resp) != C.RET_OK {
+		node.setState(StateRunning)
+		return bridge.callError("cGoCodexStop")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		node.setState(StateRunning)
+		return err
+	}
+
+	node.setState(StateStopped)
+	return nil
+}
+
+// Destroy destroys the Codex node, freeing all resources.
+// The node must be created or stopped, ErrInvalidState is returned
+// if it is running. Use Close to stop and destroy the node at once.
+func (node *CodexNode) Destroy() error {
+	previous, err := node.transition("destroy", StateDestroyed, StateCreated, StateStopped)
+	if err != nil {
+		return err
+	}
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexClose(node.ctx, bridge.resp) != C.RET_OK {
+		node.setState(previous)
+		return bridge.callError("cGoCodexClose")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		node.setState(previous)
+		return err
+	}
+
+	// The context is freed even if codex_destroy fails,
+	// so the node cannot be used anymore.
+	defer node.unregisterEvents()
+
+	if C.cGoCodexDestroy(node.ctx, bridge.resp) != C.RET_OK {
+		return errors.New("Failed to destroy the codex node.")
+	}
+
+	return nil
+}
+
+// Close stops the node if it is running and destroys it.
+// It can be called several times, the next calls do nothing.
+func (node *CodexNode) Close() error {
+	switch node.State() {
+	case StateDestroyed:
+		return nil
+	case StateRunning:
+		if err := node.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return node.Destroy()
+}
+
+// Version returns the version of the Codex node.
+func (node *CodexNode) Version() (string, error) {
+	if err := node.ensureAlive("Version"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexVersion(node.ctx, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexVersion")
+	}
+
+	return bridge.wait()
+}
+
+func (node *CodexNode) Revision() (string, error) {
+	if err := node.ensureAlive("Revision"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexRevision(node.ctx, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexRevision")
+	}
+
+	return bridge.wait()
+}
+
+// Repo returns the path of the data dir folder.
+func (node *CodexNode) Repo() (string, error) {
+	if err := node.ensureAlive("Repo"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexRepo(node.ctx, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexRepo")
+	}
+
+	return bridge.wait()
+}
+
+func (node *CodexNode) Spr() (string, error) {
+	if err := node.ensureAlive("Spr"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexSpr(node.ctx, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexSpr")
+	}
+
+	return bridge.wait()
+}
+
+func (node *CodexNode) PeerId() (string, error) {
+	if err := node.ensureAlive("PeerId"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexPeerId(node.ctx, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexPeerId")
+	}
+
+	return bridge.wait()
+}
+
+// Debug returns information about the node and its DHT routing table.
+func (node *CodexNode) Debug() (codex.DebugInfo, error) {
+	var info codex.DebugInfo
+
+	if err := node.ensureAlive("Debug"); err != nil {
+		return info, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexDebug(node.ctx, bridge.resp) != C.RET_OK {
+		return info, bridge.callError("cGoCodexDebug")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return info, err
+	}
+
+	err = json.Unmarshal([]byte(result), &info)
+	return info, err
+}
+
+// Connect connects to a peer using its peer id and optionally
+// its addresses. If no address is provided, the peer is looked
+// up in the DHT. Once connected, the peer is added to the peer book.
+func (node *CodexNode) Connect(peerId string, peerAddresses []string) error {
+	if err := node.ensureRunning("Connect"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cPeerId = C.CString(peerId)
+	defer C.free(unsafe.Pointer(cPeerId))
+
+	var cAddresses **C.char
+	if len(peerAddresses) > 0 {
+		cAddresses = (**C.char)(C.malloc(C.size_t(len(peerAddresses)) * C.size_t(unsafe.Sizeof(uintptr(0)))))
+		defer C.free(unsafe.Pointer(cAddresses))
+
+		addresses := unsafe.Slice(cAddresses, len(peerAddresses))
+		for i, addr := range peerAddresses {
+			addresses[i] = C.CString(addr)
+			defer C.free(unsafe.Pointer(addresses[i]))
+		}
+	}
+
+	if C.cGoCodexConnect(node.ctx, cPeerId, cAddresses, C.size_t(len(peerAddresses)), bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexConnect")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		return err
+	}
+
+	node.recordConnect(BookedPeer{PeerId: peerId, Addresses: peerAddresses})
+	return nil
+}
+
+// FindProviders looks up in the DHT the peers providing the content
+// identified by cid, the node itself excluded. It returns when the
+// context is done, the lookup then continues in the Codex node and
+// its result is ignored.
+func (node *CodexNode) FindProviders(ctx context.Context, cid string) ([]codex.PeerRecord, error) {
+	if err := node.ensureRunning("FindProviders"); err != nil {
+		return nil, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexFindProviders(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return nil, bridge.callError("cGoCodexFindProviders")
+	}
+
+	value, err := bridge.waitContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var providers []codex.PeerRecord
+	if err := json.Unmarshal([]byte(value), &providers); err != nil {
+		return nil, fmt.Errorf("failed to decode the providers: %w", err)
+	}
+
+	return providers, nil
+}
+
+// Peers returns the peers connected to the node, with the blocks
+// exchanged with each of them since it is connected. The peers and
+// their addresses are added to the peer book.
+func (node *CodexNode) Peers() ([]codex.Peer, error) {
+	if err := node.ensureRunning("Peers"); err != nil {
+		return nil, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexPeers(node.ctx, bridge.resp) != C.RET_OK {
+		return nil, bridge.callError("cGoCodexPeers")
+	}
+
+	value, err := bridge.wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []codex.Peer
+	if err := json.Unmarshal([]byte(value), &peers); err != nil {
+		return nil, fmt.Errorf("failed to decode the peers: %w", err)
+	}
+
+	booked := make([]BookedPeer, 0, len(peers))
+	for _, peer := range peers {
+		booked = append(booked, BookedPeer{PeerId: peer.PeerId, Addresses: peer.Addresses})
+	}
+	node.recordPeers(booked...)
+
+	return peers, nil
+}
+
+// Announce announces in the DHT that the node provides the content
+// identified by cid, without waiting for the periodic announcement.
+// For a dataset, its tree cid is announced too. The content must be
+// stored by the node. A dataset is then published: it is announced
+// periodically, even if it was uploaded with UploadOptions.Announce set
+// to false or the node is in private mode.
+func (node *CodexNode) Announce(cid string) error {
+	if err := node.ensureRunning("Announce"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexAnnounce(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexAnnounce")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// AllowPeer accepts the connections of the peer and removes it from
+// the denied peers. When Config.AllowedPeers is set, the peer is added
+// to them. It can be called before the node is started.
+func (node *CodexNode) AllowPeer(peerId string) error {
+	if err := node.ensureAlive("AllowPeer"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cPeerId = C.CString(peerId)
+	defer C.free(unsafe.Pointer(cPeerId))
+
+	if C.cGoCodexAllowPeer(node.ctx, cPeerId, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexAllowPeer")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		return err
+	}
+
+	node.setDenied(peerId, false)
+	return nil
+}
+
+// DenyPeer rejects the connections of the peer and closes its current
+// ones. Each rejected connection emits an EventPeerRejected. The peer
+// is removed from the peer book and Start does not reconnect it.
+// It can be called before the node is started.
+//
+// The denial happens after the connection is set up: the peer can still
+// connect, complete the handshakes and run identify before the node
+// closes the connection. It is never served blocks.
+func (node *CodexNode) DenyPeer(peerId string) error {
+	if err := node.ensureAlive("DenyPeer"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cPeerId = C.CString(peerId)
+	defer C.free(unsafe.Pointer(cPeerId))
+
+	if C.cGoCodexDenyPeer(node.ctx, cPeerId, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexDenyPeer")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		return err
+	}
+
+	node.setDenied(peerId, true)
+	return nil
+}
+
+// Disconnect closes the connections of the peer. Unlike DenyPeer,
+// the peer can connect again.
+func (node *CodexNode) Disconnect(peerId string) error {
+	if err := node.ensureRunning("Disconnect"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cPeerId = C.CString(peerId)
+	defer C.free(unsafe.Pointer(cPeerId))
+
+	if C.cGoCodexDisconnect(node.ctx, cPeerId, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexDisconnect")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// UploadInit initializes a new upload session.
+// It returns a session ID that can be used for subsequent upload operations.
+// options.Filename and options.Mimetype override the metadata stored in
+// the manifest, the content is not sniffed by UploadInit.
+// This function is called by UploadReader and UploadFile internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadInit(options *codex.UploadOptions) (string, error) {
+	if err := node.ensureRunning("UploadInit"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cFilepath = C.CString(options.Filepath)
+	defer C.free(unsafe.Pointer(cFilepath))
+
+	var cFilename = C.CString(options.Filename)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	var cMimetype = C.CString(options.Mimetype)
+	defer C.free(unsafe.Pointer(cMimetype))
+
+	if C.cGoCodexUploadInit(node.ctx, cFilepath, toSizeT(options.ChunkSize), cFilename, cMimetype, C.int64_t(options.TTLSeconds()), C.bool(options.AnnounceOrDefault()), bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexUploadInit")
+	}
+
+	sessionId, err := bridge.wait()
+	if err != nil {
+		return "", err
+	}
+
+	node.addUploadSession(sessionId)
+	return sessionId, nil
+}
+
+// UploadChunk uploads a chunk of data to the Codex node.
+// It takes the session ID returned by UploadInit
+// and a byte slice containing the chunk data.
+// This function is called by UploadReader internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadChunk(sessionId string, chunk []byte) error {
+	bridge, err := node.sendUploadChunk(sessionId, chunk)
+	if err != nil {
+		return err
+	}
+	defer bridge.free()
+
+	_, err = bridge.wait()
+	return err
+}
+
+// sendUploadChunk sends a chunk to the Codex node without waiting for it
+// to be stored. libcodex copies the chunk before returning, so the buffer
+// can be reused. The chunks of a session are stored in the order they are sent.
+// The caller must wait for the returned call and free it.
+func (node *CodexNode) sendUploadChunk(sessionId string, chunk []byte) (*bridgeCtx, error) {
+	if err := node.ensureSession("UploadChunk"); err != nil {
+		return nil, err
+	}
+
+	bridge := newBridgeCtx()
+	// The chunk is in flight until the caller frees the call
+	bridge.release = node.endCall
+
+	var cSessionId = C.CString(sessionId)
+	defer C.free(unsafe.Pointer(cSessionId))
+
+	var cChunkPtr *C.uint8_t
+	if len(chunk) > 0 {
+		cChunkPtr = (*C.uint8_t)(unsafe.Pointer(&chunk[0]))
+	}
+
+	if C.cGoCodexUploadChunk(node.ctx, cSessionId, cChunkPtr, C.size_t(len(chunk)), bridge.resp) != C.RET_OK {
+		err := bridge.callError("cGoCodexUploadChunk")
+		bridge.free()
+		return nil, err
+	}
+
+	return bridge, nil
+}
+
+// UploadFinalize finalizes the upload session and returns the CID of the uploaded file.
+// It takes the session ID returned by UploadInit.
+// This function is called by UploadReader and UploadFile internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadFinalize(sessionId string) (string, error) {
+	if err := node.ensureSession("UploadFinalize"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	// The Codex node ends the session, even if the finalization fails
+	defer node.removeUploadSession(sessionId)
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cSessionId = C.CString(sessionId)
+	defer C.free(unsafe.Pointer(cSessionId))
+
+	if C.cGoCodexUploadFinalize(node.ctx, cSessionId, bridge.resp) != C.RET_OK {
+		return "", bridge.callError("cGoCodexUploadFinalize")
+	}
+
+	return bridge.wait()
+}
+
+// UploadCancel cancels an ongoing upload session.
+// It can be used if the upload session is managed manually, including
+// the sessions uploading a file with UploadFileSession: the file read is
+// stopped, the blocks already stored are released and UploadFileSession
+// returns an error wrapping ErrUploadCancelled.
+func (node *CodexNode) UploadCancel(sessionId string) error {
+	if err := node.ensureSession("UploadCancel"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	defer node.removeUploadSession(sessionId)
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cSessionId = C.CString(sessionId)
+	defer C.free(unsafe.Pointer(cSessionId))
+
+	if C.cGoCodexUploadCancel(node.ctx, cSessionId, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexUploadCancel")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// UploadSession is an upload session of the node, see ListUploadSessions.
+type UploadSession struct {
+	SessionId string
+	Filepath  string
+
+	// Bytes is the size of the data received for an active session,
+	// of the data stored for an interrupted session
+	Bytes int64
+
+	CreatedAt time.Time
+	Age       time.Duration
+
+	// Interrupted is true for a session interrupted by a restart
+	// of the node, which can be resumed with ResumeUpload
+	Interrupted bool
+}
+
+// ListUploadSessions returns the upload sessions of the node: the active
+// sessions and the sessions interrupted by a restart of the node.
+func (node *CodexNode) ListUploadSessions() ([]UploadSession, error) {
+	if err := node.ensureRunning("ListUploadSessions"); err != nil {
+		return nil, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexUploadList(node.ctx, bridge.resp) != C.RET_OK {
+		return nil, bridge.callError("cGoCodexUploadList")
+	}
+
+	value, err := bridge.wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		SessionId   string `json:"sessionId"`
+		Filepath    string `json:"filepath"`
+		Bytes       int64  `json:"bytes"`
+		CreatedAt   int64  `json:"createdAt"`
+		Interrupted bool   `json:"interrupted"`
+	}
+
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode the upload sessions: %w", err)
+	}
+
+	now := time.Now()
+	sessions := make([]UploadSession, 0, len(entries))
+	for _, entry := range entries {
+		createdAt := time.Unix(entry.CreatedAt, 0)
+
+		sessions = append(sessions, UploadSession{
+			SessionId:   entry.SessionId,
+			Filepath:    entry.Filepath,
+			Bytes:       entry.Bytes,
+			CreatedAt:   createdAt,
+			Age:         now.Sub(createdAt),
+			Interrupted: entry.Interrupted,
+		})
+	}
+
+	return sessions, nil
+}
+
+// ResumedUpload is an upload session restored by UploadResume.
+type ResumedUpload struct {
+	SessionId string `json:"sessionId"`
+	Filepath  string `json:"filepath"`
+	ChunkSize int    `json:"chunkSize"`
+
+	// Bytes is the size of the data already stored,
+	// the upload continues after it.
+	Bytes int64 `json:"bytes"`
+}
+
+// UploadResume restores an upload session interrupted by a restart
+// of the node, from the journal kept in the data dir.
+// The data following the bytes already stored must then be sent with
+// UploadChunk and the upload finalized with UploadFinalize.
+// This function is called by ResumeUpload internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadResume(sessionId string) (ResumedUpload, error) {
+	if err := node.ensureRunning("UploadResume"); err != nil {
+		return ResumedUpload{}, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cSessionId = C.CString(sessionId)
+	defer C.free(unsafe.Pointer(cSessionId))
+
+	if C.cGoCodexUploadResume(node.ctx, cSessionId, bridge.resp) != C.RET_OK {
+		return ResumedUpload{}, bridge.callError("cGoCodexUploadResume")
+	}
+
+	value, err := bridge.wait()
+	if err != nil {
+		return ResumedUpload{}, err
+	}
+
+	// The session is restored, it must be finalized or cancelled
+	node.addUploadSession(sessionId)
+
+	var resumed ResumedUpload
+	if err := json.Unmarshal([]byte(value), &resumed); err != nil {
+		return ResumedUpload{}, fmt.Errorf("failed to decode the resumed upload: %w", err)
+	}
+
+	return resumed, nil
+}
+
+// UploadReader uploads data from an io.Reader to the Codex node.
+// It takes the upload options and the reader as parameters.
+// It returns the CID of the uploaded file or an error.
+//
+// Internally, it calls:
+// - UploadInit to create the upload session.
+// - UploadChunk to upload a chunk to codex.
+// - UploadFinalize to finalize the upload session.
+// - UploadCancel if an error occurs.
+//
+// When options.Concurrency is more than 1, the chunks are read ahead
+// and sent without waiting for the previous ones to be stored, see
+// uploadPipelined.
+func (node *CodexNode) UploadReader(options codex.UploadOptions, r io.Reader) (string, error) {
+	options, r, err := codex.SniffMimetype(options, r)
+	if err != nil {
+		return "", err
+	}
+
+	sessionId, err := node.UploadInit(&options)
+	if err != nil {
+		return "", err
+	}
+
+	return node.UploadReaderSession(sessionId, options, r)
+}
+
+// UploadReaderSession uploads the data of r to an upload session created
+// by UploadInit or restored by UploadResume, and finalizes it.
+// The session is cancelled on error.
+// This function is called by UploadReader and ResumeUpload internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadReaderSession(sessionId string, options codex.UploadOptions, r io.Reader) (string, error) {
+	if options.Concurrency > 1 {
+		if err := node.uploadPipelined(sessionId, options, r); err != nil {
+			if cancelErr := node.UploadCancel(sessionId); cancelErr != nil {
+				return "", fmt.Errorf("failed to upload chunk %v and failed to cancel upload session %v", err, cancelErr)
+			}
+
+			return "", err
+		}
+
+		return node.UploadFinalize(sessionId)
+	}
+
+	buf := make([]byte, options.ChunkSize.ValOrDefault())
+	total := 0
+
+	var size int64
+	if options.OnProgress != nil {
+		size = codex.ReaderSize(r)
+	}
+
+	cancel := func(err error) (string, error) {
+		if cancelErr := node.UploadCancel(sessionId); cancelErr != nil {
+			return "", fmt.Errorf("failed to upload chunk %v and failed to cancel upload session %v", err, cancelErr)
+		}
+
+		return "", err
+	}
+
+	empty := 0
+	for {
+		n, err := r.Read(buf)
+
+		// The bytes read come before the error, io.EOF included
+		if n > 0 {
+			empty = 0
+			if err := node.UploadChunk(sessionId, buf[:n]); err != nil {
+				return cancel(err)
+			}
+
+			total += n
+			if options.OnProgress != nil {
+				err := safeCallback(func() {
+					options.OnProgress(n, total, codex.Percent(total, size), nil)
+				})
+
+				if err != nil {
+					return "", errors.Join(fmt.Errorf("failed to deliver the progress: %w", err), node.UploadCancel(sessionId))
+				}
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return cancel(err)
+		}
+
+		if n == 0 {
+			if empty++; empty == maxEmptyReads {
+				return cancel(io.ErrNoProgress)
+			}
+		}
+	}
+
+	return node.UploadFinalize(sessionId)
+}
+
+// ResumeUpload resumes an upload session interrupted by a restart of
+// the node, see UploadResume. r must return the same data as the reader
+// of the interrupted upload: the bytes already stored are skipped, with
+// Seek if r is an io.Seeker, then the upload continues like UploadReader.
+// It returns the CID of the whole content.
+//
+// options.OnProgress, options.Progress and options.Concurrency apply
+// to the rest of the upload, the progress counts the bytes sent after
+// the resume. The file path and the chunk size are the ones of the
+// session.
+//
+// Internally, it calls UploadResume then UploadReaderSession.
+func (node *CodexNode) ResumeUpload(sessionId string, options codex.UploadOptions, r io.Reader) (string, error) {
+	resumed, err := node.UploadResume(sessionId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := skip(r, resumed.Bytes); err != nil {
+		err = fmt.Errorf("failed to skip the %d bytes already uploaded: %w", resumed.Bytes, err)
+		return "", errors.Join(err, node.UploadCancel(sessionId))
+	}
+
+	options.Filepath = resumed.Filepath
+	options.ChunkSize = codex.ChunkSize(resumed.ChunkSize)
+
+	return node.UploadReaderSession(sessionId, options, r)
+}
+
+// skip advances r by n bytes.
+func skip(r io.Reader, n int64) error {
+	if n == 0 {
+		return nil
+	}
+
+	if seeker, ok := r.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekCurrent)
+		return err
+	}
+
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// UploadReaderAsync is the asynchronous version of UploadReader using a goroutine.
+func (node *CodexNode) UploadReaderAsync(options codex.UploadOptions, r io.Reader, onDone func(cid string, err error)) {
+	go func() {
+		cid, err := node.UploadReader(options, r)
+		onDone(cid, err)
+	}()
+}
+
+// UploadFile uploads a file to the Codex node.
+// It takes the upload options as parameter.
+// It returns the CID of the uploaded file or an error.
+//
+// The options parameter contains the following fields:
+// - filepath: the full path of the file to upload.
+// - chunkSize: the size of each upload chunk, passed as `blockSize` to the Codex node
+// store. Default is to 64 KB.
+// - onProgress: a callback function that is called after each chunk is uploaded with:
+//   - read: the number of bytes read in the last chunk.
+//   - total: the total number of bytes read so far.
+//   - percent: the percentage of the total file size that has been uploaded. It is
+//     determined from a `stat` call.
+//   - err: an error, if one occurred.
+//
+// If the chunk size is more than the `chunkSize` parameter, the callback is called after
+// the block is actually stored in the block store. Otherwise, it is called after the chunk
+// is sent to the stream.
+//
+// Internally, it calls UploadInit to create the upload session.
+func (node *CodexNode) UploadFile(options codex.UploadOptions) (string, error) {
+	return node.UploadFileContext(context.Background(), options)
+}
+
+// UploadFileContext is UploadFile with a context: when the context is done,
+// the upload session is cancelled and an error wrapping ErrUploadCancelled
+// and the context error is returned.
+func (node *CodexNode) UploadFileContext(ctx context.Context, options codex.UploadOptions) (string, error) {
+	options, err := codex.SniffFileMimetype(options)
+	if err != nil {
+		return "", err
+	}
+
+	sessionId, err := node.UploadInit(&options)
+	if err != nil {
+		return "", err
+	}
+
+	return node.UploadFileSession(ctx, sessionId, options)
+}
+
+// UploadFileSession uploads the file of an upload session created by
+// UploadInit with an absolute options.Filepath.
+// The upload can be cancelled with UploadCancel or with the context.
+// This function is called by UploadFile internally.
+// You should use this function only if you need to manage the upload session manually.
+func (node *CodexNode) UploadFileSession(ctx context.Context, sessionId string, options codex.UploadOptions) (string, error) {
+	return node.uploadFile(ctx, sessionId, options, false)
+}
+
+// uploadFile uploads the file of the session. With described, the node
+// returns the uploaded file in JSON instead of the CID, see uploadedFile.
+func (node *CodexNode) uploadFile(ctx context.Context, sessionId string, options codex.UploadOptions, described bool) (string, error) {
+	defer node.removeUploadSession(sessionId)
+
+	if err := node.ensureSession("UploadFileSession"); err != nil {
+		return "", err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var onProgress func(read int, _ []byte)
+	if options.OnProgress != nil {
+		stat, err := os.Stat(options.Filepath)
+		if err != nil {
+			return "", errors.Join(err, node.UploadCancel(sessionId))
+		}
+
+		size := stat.Size()
+		total := 0
+
+		if size > 0 {
+			onProgress = func(read int, _ []byte) {
+				if read == 0 {
+					return
+				}
+
+				total += read
+				// The last block could be a bit over the size due to padding
+				// on the chunk size, Percent caps it to 100.
+				options.OnProgress(read, total, codex.Percent(total, size), nil)
+			}
+		}
+	}
+
+	var cSessionId = C.CString(sessionId)
+	defer C.free(unsafe.Pointer(cSessionId))
+
+	// The progress is delivered from a goroutine, not from the libcodex thread
+	var progress *progressQueue
+	if onProgress != nil {
+		progress = newProgressQueue(options.Progress, true, onProgress)
+		bridge.onProgress = progress.push
+	}
+
+	if described {
+		if C.cGoCodexUploadFileResult(node.ctx, cSessionId, bridge.resp) != C.RET_OK {
+			progress.close()
+			return "", bridge.callError("cGoCodexUploadFileResult")
+		}
+	} else if C.cGoCodexUploadFile(node.ctx, cSessionId, bridge.resp) != C.RET_OK {
+		progress.close()
+		return "", bridge.callError("cGoCodexUploadFile")
+	}
+
+	// Cancel the session when the context is done before the upload
+	uploaded := make(chan struct{})
+	cancelled := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelled <- node.UploadCancel(sessionId)
+		case <-uploaded:
+			close(cancelled)
+		}
+	}()
+
+	cid, err := bridge.wait()
+	close(uploaded)
+	cancelErr := <-cancelled
+
+	if progressErr := progress.close(); progressErr != nil {
+		return "", errors.Join(err, progressErr)
+	}
+
+	// The upload may have completed before the cancellation
+	if err == nil {
+		return cid, nil
+	}
+
+	if ctx.Err() != nil {
+		return "", errors.Join(fmt.Errorf("%w: %w", ErrUploadCancelled, context.Cause(ctx)), cancelErr)
+	}
+
+	if isUploadCancelled(err) {
+		return "", fmt.Errorf("%w: %w", ErrUploadCancelled, err)
+	}
+
+	return "", err
+}
+
+// UploadFileAsync is the asynchronous version of UploadFile using a goroutine.
+func (node *CodexNode) UploadFileAsync(options codex.UploadOptions, onDone func(cid string, err error)) {
+	go func() {
+		cid, err := node.UploadFile(options)
+		onDone(cid, err)
+	}()
+}
+
+func (node *CodexNode) UpdateLogLevel(logLevel string) error {
+	if err := node.ensureAlive("UpdateLogLevel"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cLogLevel = C.CString(string(logLevel))
+	defer C.free(unsafe.Pointer(cLogLevel))
+
+	if C.cGoCodexLogLevel(node.ctx, cLogLevel, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexLogLevel")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+func (node *CodexNode) Exists(cid string) (bool, error) {
+	if err := node.ensureRunning("Exists"); err != nil {
+		return false, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexExists(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return false, bridge.callError("cGoCodexExists")
+	}
+
+	result, err := bridge.wait()
+	return result == "true", err
+}
+
+// SetExpiry sets the expiry of the content identified by cid: the
+// manifest block and the blocks of the dataset stored locally. The
+// blocks are deleted by the node maintenance once the expiry is reached.
+// Unlike the expiry set by the node, it can be sooner than the current one,
+// except for the blocks shared with other datasets, which keep the latest.
+// The manifest must be stored locally.
+func (node *CodexNode) SetExpiry(cid string, expiry time.Time) error {
+	if err := node.ensureRunning("SetExpiry"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	if expiry.Unix() <= 0 {
+		return fmt.Errorf("failed to set the expiry: invalid time %v", expiry)
+	}
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexSetExpiry(node.ctx, cCid, C.int64_t(expiry.Unix()), bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexSetExpiry")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// Expiry returns the earliest expiry of the blocks of the content
+// identified by cid stored locally, when the content starts to be deleted.
+// The manifest must be stored locally.
+func (node *CodexNode) Expiry(cid string) (time.Time, error) {
+	if err := node.ensureRunning("Expiry"); err != nil {
+		return time.Time{}, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexExpiry(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return time.Time{}, bridge.callError("cGoCodexExpiry")
+	}
+
+	value, err := bridge.wait()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse the expiry %q: %w", value, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+// DownloadInit initializes a download session for the given cid.
+// Only one session can be active for a cid.
+// This function is called by DownloadStream internally.
+// You should use this function only if you need to manage the download session manually.
+func (node *CodexNode) DownloadInit(cid string, options codex.DownloadStreamOptions) error {
+	if err := node.ensureRunning("DownloadInit"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexDownloadInit(node.ctx, cCid, toSizeT(options.ChunkSize), C.bool(options.Local), bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexDownloadInit")
+	}
+
+	if _, err := bridge.wait(); err != nil {
+		return err
+	}
+
+	node.addDownloadSession(cid)
+	return nil
+}
+
+// DownloadChunk downloads the next chunk of the session initialized
+// with DownloadInit. It returns io.EOF when the content is fully downloaded.
+func (node *CodexNode) DownloadChunk(cid string) ([]byte, error) {
+	if err := node.ensureSession("DownloadChunk"); err != nil {
+		return nil, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var chunk []byte
+	bridge.onProgress = func(_ int, c []byte) {
+		chunk = c
+	}
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexDownloadChunk(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return nil, bridge.callError("cGoCodexDownloadChunk")
+	}
+
+	// The transfer is over on error and when the content is fully downloaded
+	if _, err := bridge.wait(); err != nil {
+		node.removeDownloadSession(cid)
+		return nil, err
+	}
+
+	if chunk == nil {
+		node.removeDownloadSession(cid)
+		return nil, io.EOF
+	}
+
+	return chunk, nil
+}
+
+// DownloadCancel cancels the download session of the given cid.
+// It doesn't work with DownloadStream.
+func (node *CodexNode) DownloadCancel(cid string) error {
+	if err := node.ensureSession("DownloadCancel"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	defer node.removeDownloadSession(cid)
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexDownloadCancel(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexDownloadCancel")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// DownloadStream downloads the content identified by cid in a streaming
+// manner. Each chunk received is written to options.Writer if it is set,
+// and the Codex node writes the content to options.Filepath if it is set.
+//
+// Internally, it calls:
+// - DownloadManifest if options.DatasetSizeAuto is set.
+// - DownloadInit to create the download session.
+func (node *CodexNode) DownloadStream(cid string, options codex.DownloadStreamOptions) error {
+	if options.DatasetSizeAuto {
+		manifest, err := node.DownloadManifest(cid)
+		if err != nil {
+			return err
+		}
+
+		options.DatasetSize = int(manifest.DatasetSize)
+	}
+
+	if err := node.DownloadInit(cid, options); err != nil {
+		return err
+	}
+
+	defer node.removeDownloadSession(cid)
+
+	if err := node.ensureSession("DownloadStream"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	total := 0
+	var writeErr error
+	onProgress := func(read int, chunk []byte) {
+		if read == 0 {
+			return
+		}
+
+		if options.Writer != nil && writeErr == nil {
+			if _, err := options.Writer.Write(chunk); err != nil {
+				writeErr = err
+			}
+		}
+
+		total += read
+		if options.OnProgress != nil {
+			options.OnProgress(read, total, codex.Percent(total, int64(options.DatasetSize)), writeErr)
+		}
+	}
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	var cFilepath = C.CString(options.Filepath)
+	defer C.free(unsafe.Pointer(cFilepath))
+
+	// The chunks are written and the progress is delivered from a goroutine,
+	// not from the libcodex thread. The chunks cannot be coalesced when
+	// they are written.
+	progress := newProgressQueue(options.Progress, options.Writer == nil, onProgress)
+	bridge.onProgress = progress.push
+
+	if C.cGoCodexDownloadStream(node.ctx, cCid, toSizeT(options.ChunkSize), C.bool(options.Local), cFilepath, bridge.resp) != C.RET_OK {
+		progress.close()
+		return bridge.callError("cGoCodexDownloadStream")
+	}
+
+	_, err := bridge.wait()
+	if progressErr := progress.close(); progressErr != nil {
+		return errors.Join(err, progressErr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return writeErr
+}
+
+// DownloadManifest retrieves the manifest of the content identified by cid,
+// from the local store or from the network.
+func (node *CodexNode) DownloadManifest(cid string) (codex.Manifest, error) {
+	manifest := codex.Manifest{Cid: cid}
+
+	if err := node.ensureRunning("DownloadManifest"); err != nil {
+		return manifest, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexDownloadManifest(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return manifest, bridge.callError("cGoCodexDownloadManifest")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return manifest, err
+	}
+
+	err = json.Unmarshal([]byte(result), &manifest)
+	manifest.Cid = cid
+	return manifest, err
+}
+
+// ManifestEntry is a manifest stored by the node, the format of each item
+// returned by codex_storage_list and codex_storage_list_page.
+type ManifestEntry struct {
+	Cid string `json:"cid"`
+
+	// Manifest.Cid is set to Cid.
+	Manifest codex.Manifest `json:"manifest"`
+}
+
+// List returns the manifests stored by the node.
+func (node *CodexNode) List() ([]codex.Manifest, error) {
+	if err := node.ensureRunning("List"); err != nil {
+		return nil, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexList(node.ctx, bridge.resp) != C.RET_OK {
+		return nil, bridge.callError("cGoCodexList")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []ManifestEntry
+	if err := json.Unmarshal([]byte(result), &items); err != nil {
+		return nil, err
+	}
+
+	manifests := make([]codex.Manifest, 0, len(items))
+	for _, item := range items {
+		item.Manifest.Cid = item.Cid
+		manifests = append(manifests, item.Manifest)
+	}
+
+	return manifests, nil
+}
+
+// ListPage returns a page of the manifests stored by the node, following
+// options.Cursor. Page.Next is the cursor of the next page, empty on the
+// last page. See Manifests to iterate over all the pages.
+func (node *CodexNode) ListPage(options ListOptions) (ManifestPage, error) {
+	var page ManifestPage
+
+	if err := node.ensureRunning("ListPage"); err != nil {
+		return page, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCursor = C.CString(options.Cursor)
+	defer C.free(unsafe.Pointer(cCursor))
+
+	var cMimetype = C.CString(options.Mimetype)
+	defer C.free(unsafe.Pointer(cMimetype))
+
+	var cFilenamePrefix = C.CString(options.FilenamePrefix)
+	defer C.free(unsafe.Pointer(cFilenamePrefix))
+
+	if C.cGoCodexListPage(node.ctx, cCursor, C.size_t(max(options.Limit, 0)), cMimetype, cFilenamePrefix, bridge.resp) != C.RET_OK {
+		return page, bridge.callError("cGoCodexListPage")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return page, err
+	}
+
+	if err := json.Unmarshal([]byte(result), &page); err != nil {
+		return page, fmt.Errorf("failed to decode the manifests page: %w", err)
+	}
+
+	for i := range page.Items {
+		page.Items[i].Manifest.Cid = page.Items[i].Cid
+	}
+
+	return page, nil
+}
+
+// Space returns the storage usage of the node.
+func (node *CodexNode) Space() (codex.Space, error) {
+	var space codex.Space
+
+	if err := node.ensureRunning("Space"); err != nil {
+		return space, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	if C.cGoCodexSpace(node.ctx, bridge.resp) != C.RET_OK {
+		return space, bridge.callError("cGoCodexSpace")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return space, err
+	}
+
+	err = json.Unmarshal([]byte(result), &space)
+	return space, err
+}
+
+// Delete deletes either a single block or an entire dataset from the local node.
+func (node *CodexNode) Delete(cid string) error {
+	if err := node.ensureRunning("Delete"); err != nil {
+		return err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexDelete(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return bridge.callError("cGoCodexDelete")
+	}
+
+	_, err := bridge.wait()
+	return err
+}
+
+// Fetch downloads the dataset identified by cid from the network to the
+// local node. The download runs in the background, the function returns
+// as soon as the manifest is retrieved.
+func (node *CodexNode) Fetch(cid string) (codex.Manifest, error) {
+	manifest := codex.Manifest{Cid: cid}
+
+	if err := node.ensureRunning("Fetch"); err != nil {
+		return manifest, err
+	}
+	defer node.endCall()
+
+	bridge := newBridgeCtx()
+	defer bridge.free()
+
+	var cCid = C.CString(cid)
+	defer C.free(unsafe.Pointer(cCid))
+
+	if C.cGoCodexFetch(node.ctx, cCid, bridge.resp) != C.RET_OK {
+		return manifest, bridge.callError("cGoCodexFetch")
+	}
+
+	result, err := bridge.wait()
+	if err != nil {
+		return manifest, err
+	}
+
+	err = json.Unmarshal([]byte(result), &manifest)
+	manifest.Cid = cid
+	return manifest, err
+}
@@ -0,0 +1,259 @@
+package libcodex
+
+/*
+	#include <stdint.h>
+	#include "../../../../library/libcodex.h"
+
+	// Calls are identified by the id passed as user data to the callback
+	static void* callId(uintptr_t id) {
+		return (void*) id;
+	}
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// callState is the state of a call in the dispatcher.
+//
+//	pending -> progress -> done
+//	   |                    ^
+//	   +--------------------+
+type callState int32
+
+const (
+	// The call is sent to the Codex node, no callback received yet
+	callPending callState = iota
+
+	// At least one progress callback was received
+	callProgress
+
+	// The call completed with RET_OK or RET_ERR, next callbacks are ignored
+	callDone
+)
+
+// callResult is the completion of a call, delivered through a channel.
+type callResult struct {
+	result string
+	err    error
+}
+
+// CallStats are the counters of the dispatcher, for debugging.
+type CallStats struct {
+	// Calls sent to the Codex node and not freed yet
+	InFlight int
+
+	// Calls started since the process started
+	Started uint64
+
+	// Calls completed with RET_OK or RET_ERR
+	Completed uint64
+
+	// Callbacks received for a completed call, or for a call
+	// which is not in flight anymore. They are ignored.
+	Duplicates uint64
+}
+
+// dispatcher routes the callbacks of the Codex node to the calls in flight.
+// The C side receives a call id as user data instead of a Go pointer,
+// so a late callback for a freed call is detected and ignored.
+type dispatcher struct {
+	mu     sync.Mutex
+	nextId uintptr
+	calls  map[uintptr]*bridgeCtx
+
+	started    atomic.Uint64
+	completed  atomic.Uint64
+	duplicates atomic.Uint64
+}
+
+var calls = &dispatcher{calls: map[uintptr]*bridgeCtx{}}
+
+// Stats returns the counters of the calls made to the Codex nodes
+// of the process.
+func Stats() CallStats {
+	calls.mu.Lock()
+	inFlight := len(calls.calls)
+	calls.mu.Unlock()
+
+	return CallStats{
+		InFlight:   inFlight,
+		Started:    calls.started.Load(),
+		Completed:  calls.completed.Load(),
+		Duplicates: calls.duplicates.Load(),
+	}
+}
+
+func (d *dispatcher) register(b *bridgeCtx) uintptr {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nextId++
+	d.calls[d.nextId] = b
+	d.started.Add(1)
+
+	return d.nextId
+}
+
+func (d *dispatcher) unregister(id uintptr) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.calls, id)
+}
+
+func (d *dispatcher) lookup(id uintptr) *bridgeCtx {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.calls[id]
+}
+
+// bridgeCtx is a call made to the Codex node.
+// It is registered in the dispatcher until free is called,
+// its result is delivered through the done channel.
+type bridgeCtx struct {
+	id   uintptr
+	resp unsafe.Pointer
+
+	state atomic.Int32
+
+	// Return code of the last callback, used in the call errors
+	ret atomic.Int32
+
+	// Buffered, receives the first completion only
+	done chan callResult
+
+	// Callback used for receiving progress updates during upload/download.
+	//
+	// For the upload, the bytes parameter indicates the number of bytes uploaded.
+	// If the chunk size is superior or equal to the blocksize (passed in init function),
+	// the callback will be called when a block is put in the store.
+	// Otherwise, it will be called when a chunk is pushed into the stream.
+	//
+	// For the download, the bytes is the size of the chunk received, and the chunk
+	// is the actual chunk of data received.
+	onProgress func(bytes int, chunk []byte)
+
+	// Called once by free, to end the call on the node
+	release func()
+}
+
+// newBridgeCtx registers a new call in the dispatcher.
+// resp is the user data to pass to the C function.
+func newBridgeCtx() *bridgeCtx {
+	bridge := &bridgeCtx{done: make(chan callResult, 1)}
+	bridge.id = calls.register(bridge)
+	bridge.resp = C.callId(C.uintptr_t(bridge.id))
+	return bridge
+}
+
+// callError creates an error message for a failed C-Go call.
+// When the Codex node already reported an error, it is wrapped.
+func (b *bridgeCtx) callError(name string) error {
+	err := fmt.Errorf("failed the call to %s returned code %d", name, b.ret.Load())
+
+	select {
+	case r := <-b.done:
+		if r.err != nil {
+			return fmt.Errorf("%w: %w", err, r.err)
+		}
+	default:
+	}
+
+	return err
+}
+
+// free unregisters the call, the next callbacks are ignored.
+func (b *bridgeCtx) free() {
+	if b.id > 0 {
+		calls.unregister(b.id)
+		b.id = 0
+	}
+
+	if b.release != nil {
+		b.release()
+		b.release = nil
+	}
+}
+
+// progress handles a RET_PROGRESS callback.
+func (b *bridgeCtx) progress(bytes int, chunk []byte) {
+	if !b.state.CompareAndSwap(int32(callPending), int32(callProgress)) &&
+		callState(b.state.Load()) == callDone {
+		calls.duplicates.Add(1)
+		return
+	}
+
+	if b.onProgress != nil {
+		b.onProgress(bytes, chunk)
+	}
+}
+
+// complete handles a RET_OK or RET_ERR callback.
+// Only the first completion is delivered.
+func (b *bridgeCtx) complete(r callResult) {
+	if callState(b.state.Swap(int32(callDone))) == callDone {
+		calls.duplicates.Add(1)
+		return
+	}
+
+	calls.completed.Add(1)
+	b.done <- r
+}
+
+// callback is the function called by the C code to communicate back to Go.
+// It handles progress updates, successful completions, and errors.
+// The user data is the id of the call, used to retrieve the bridge context.
+//
+//export callback
+func callback(ret C.int, msg *C.char, len C.size_t, resp unsafe.Pointer) {
+	if resp == nil {
+		return
+	}
+
+	b := calls.lookup(uintptr(resp))
+	if b == nil {
+		calls.duplicates.Add(1)
+		return
+	}
+
+	b.ret.Store(int32(ret))
+
+	switch ret {
+	case C.RET_PROGRESS:
+		if msg != nil {
+			b.progress(int(len), C.GoBytes(unsafe.Pointer(msg), C.int(len)))
+		} else {
+			b.progress(int(len), nil)
+		}
+	case C.RET_OK:
+		b.complete(callResult{result: C.GoStringN(msg, C.int(len))})
+	case C.RET_ERR:
+		b.complete(callResult{err: errors.New(C.GoStringN(msg, C.int(len)))})
+	}
+}
+
+// wait waits for the completion of the call.
+// It returns the result and error of the operation.
+func (b *bridgeCtx) wait() (string, error) {
+	r := <-b.done
+	return r.result, r.err
+}
+
+// waitContext is wait returning the context error when the context is
+// done first. The call is not cancelled in the Codex node, its result
+// is ignored once free is called.
+func (b *bridgeCtx) waitContext(ctx context.Context) (string, error) {
+	select {
+	case r := <-b.done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
@@ -0,0 +1,144 @@
+package libcodex
+
+/*
+	#include <stdint.h>
+	#include "../../../../library/libcodex.h"
+
+	void eventCallback(int ret, char* msg, size_t len, void* userData);
+
+	static void cGoCodexSetEventCallback(void* codexCtx, uintptr_t h) {
+		codex_set_event_callback(codexCtx, (CodexCallback) eventCallback, (void*) h);
+	}
+*/
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// EventUploadSessionExpired is emitted when the node cancels an upload
+// session without activity for Config.UploadSessionIdleTimeout, with the
+// reason "idle", or releases an interrupted session which was not resumed
+// within Config.UploadSessionTtl, with the reason "ttl".
+// The data is an UploadSessionExpired.
+const EventUploadSessionExpired = "uploadSessionExpired"
+
+// UploadSessionExpired is the data of EventUploadSessionExpired.
+type UploadSessionExpired struct {
+	SessionId string `json:"sessionId"`
+	Filepath  string `json:"filepath"`
+	Bytes     int64  `json:"bytes"`
+	Reason    string `json:"reason"`
+}
+
+// EventPeerRejected is emitted when the node closes a connection of a
+// peer which is denied or not allowed, once the connection is set up,
+// see Config.AllowedPeers,
+// Config.DeniedPeers and CodexNode.DenyPeer. The data is a PeerRejected.
+const EventPeerRejected = "peerRejected"
+
+// PeerRejected is the data of EventPeerRejected.
+type PeerRejected struct {
+	PeerId string `json:"peerId"`
+	// Direction is "inbound" for a connection of the peer and
+	// "outbound" for a connection to the peer.
+	Direction string `json:"direction"`
+}
+
+// Event is a notification sent by the Codex node, outside of any call.
+type Event struct {
+	// Type is the eventType field of the event
+	Type string
+
+	// Data is the JSON payload of the event, including the eventType field
+	Data json.RawMessage
+
+	// Err is set when the Codex node failed to build the event
+	Err error
+}
+
+// OnEvent sets the handler receiving the events of the node,
+// replacing the previous one. A nil handler drops the events.
+//
+// Each node has its own handler, the events of the other nodes
+// running in the process are not received. The handler is called
+// from the libcodex thread of the node: it must return quickly
+// and must not call the node.
+func (node *CodexNode) OnEvent(handler func(Event)) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.onEvent = handler
+}
+
+// registerEvents routes the events of the Codex context to the node.
+// The handle is released by Destroy.
+func (node *CodexNode) registerEvents() {
+	node.events = cgo.NewHandle(node)
+	C.cGoCodexSetEventCallback(node.ctx, C.uintptr_t(node.events))
+}
+
+// unregisterEvents releases the handle once the context is destroyed.
+func (node *CodexNode) unregisterEvents() {
+	if node.events != 0 {
+		node.events.Delete()
+		node.events = 0
+	}
+}
+
+// eventCallback is the function called by the C code to notify the events.
+// The user data is the handle of the node which created the context.
+//
+//export eventCallback
+func eventCallback(ret C.int, msg *C.char, len C.size_t, userData unsafe.Pointer) {
+	if userData == nil {
+		return
+	}
+
+	node, ok := cgo.Handle(uintptr(userData)).Value().(*CodexNode)
+	if !ok {
+		return
+	}
+
+	data := C.GoBytes(unsafe.Pointer(msg), C.int(len))
+
+	var event Event
+	if ret != C.RET_OK {
+		event.Err = errors.New(string(data))
+	} else {
+		event = decodeEvent(data)
+	}
+
+	// The session cancelled by the node is over
+	if event.Type == EventUploadSessionExpired {
+		var expired UploadSessionExpired
+		if err := json.Unmarshal(data, &expired); err == nil {
+			node.removeUploadSession(expired.SessionId)
+		}
+	}
+
+	node.mu.Lock()
+	handler := node.onEvent
+	node.mu.Unlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+func decodeEvent(data []byte) Event {
+	event := Event{Data: data}
+	var header struct {
+		EventType string `json:"eventType"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		event.Err = err
+	} else {
+		event.Type = header.EventType
+	}
+
+	return event
+}
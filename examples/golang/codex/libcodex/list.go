@@ -0,0 +1,70 @@
+package libcodex
+
+import (
+	"context"
+	"iter"
+)
+
+// ListOptions pages and filters the manifests listed by ListPage
+// and Manifests.
+type ListOptions struct {
+	// Limit is the maximum number of manifests of a page.
+	// Default is 100.
+	Limit int
+
+	// Cursor is the Next cursor of the previous page,
+	// empty for the first page.
+	Cursor string
+
+	// Mimetype keeps the manifests with this mimetype only.
+	Mimetype string
+
+	// FilenamePrefix keeps the manifests whose filename starts
+	// with this prefix only.
+	FilenamePrefix string
+}
+
+// ManifestPage is a page of the manifests stored by the node, listed
+// in the key order of the store: by the last characters of the cid,
+// then by cid. Only the last page has less than Limit manifests.
+type ManifestPage struct {
+	Items []ManifestEntry `json:"items"`
+
+	// Next is the cursor of the next page, the cid of the last
+	// manifest of the page, empty on the last page.
+	Next string `json:"next"`
+}
+
+// Manifests iterates over the manifests stored by the node, from
+// options.Cursor. The pages are requested lazily, when the previous
+// one is consumed, so breaking out of the loop stops the listing.
+// An error ends the iteration, it is yielded with a zero entry, like
+// the context error when the context is done between two pages.
+func (node *CodexNode) Manifests(ctx context.Context, options ListOptions) iter.Seq2[ManifestEntry, error] {
+	return func(yield func(ManifestEntry, error) bool) {
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(ManifestEntry{}, err)
+				return
+			}
+
+			page, err := node.ListPage(options)
+			if err != nil {
+				yield(ManifestEntry{}, err)
+				return
+			}
+
+			for _, entry := range page.Items {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+
+			if page.Next == "" {
+				return
+			}
+
+			options.Cursor = page.Next
+		}
+	}
+}
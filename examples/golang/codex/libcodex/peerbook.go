@@ -0,0 +1,359 @@
+package libcodex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PeerBookFile is the file of the peer book in the data dir of the node.
+const PeerBookFile = "peers.json"
+
+// DefaultReconnectPeers is the number of peers of the peer book
+// reconnected by Start.
+const DefaultReconnectPeers = 10
+
+const (
+	// Attempts to reconnect a peer before giving up until the next Start
+	reconnectAttempts = 5
+
+	// Delay before the second attempt, doubled after each failure
+	reconnectBackoff = time.Second
+
+	reconnectMaxBackoff = 30 * time.Second
+
+	// Delay between two writes of the peer book when only the times of
+	// its peers changed
+	peerBookSaveInterval = time.Minute
+
+	// Peers kept in the peer book, the least recent ones are dropped
+	maxBookedPeers = 100
+)
+
+// BookedPeer is a peer of the peer book.
+type BookedPeer struct {
+	PeerId string `json:"peerId"`
+
+	// Addresses are the multiaddresses of the peer when it was last seen.
+	Addresses []string `json:"addresses"`
+
+	// LastSuccess is the last time Connect succeeded with the peer,
+	// zero if the peer was only listed by Peers.
+	LastSuccess time.Time `json:"lastSuccess"`
+
+	// LastSeen is the last time the peer was listed by Peers.
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// moreRecent orders the peers connected with Connect first, then the
+// peers listed by Peers, by decreasing time.
+func moreRecent(a, b BookedPeer) int {
+	if c := b.LastSuccess.Compare(a.LastSuccess); c != 0 {
+		return c
+	}
+
+	return b.LastSeen.Compare(a.LastSeen)
+}
+
+// peerBook keeps the peers the node was connected to, in a file of the
+// data dir, to reconnect to them after a restart.
+type peerBook struct {
+	mu    sync.Mutex
+	path  string
+	peers map[string]BookedPeer
+
+	// Time of the last write, and whether the peers changed since
+	saved time.Time
+	dirty bool
+}
+
+// loadPeerBook reads the peer book file. A missing or invalid file is an
+// empty peer book: the peers are found again through the bootstrap nodes.
+func loadPeerBook(path string) *peerBook {
+	book := &peerBook{path: path, peers: map[string]BookedPeer{}, saved: time.Now()}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return book
+	}
+
+	var peers []BookedPeer
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return book
+	}
+
+	for _, peer := range peers {
+		book.peers[peer.PeerId] = peer
+	}
+
+	return book
+}
+
+// update applies the change to the peer, created if needed. The addresses
+// of the peer are kept when none is given. The file is written when a peer
+// or its addresses changed, otherwise at most every peerBookSaveInterval,
+// so that polling Peers does not write it each time.
+func (book *peerBook) update(change func(*BookedPeer), peers ...BookedPeer) error {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	changed := false
+	for _, peer := range peers {
+		booked, ok := book.peers[peer.PeerId]
+		if !ok {
+			booked = BookedPeer{PeerId: peer.PeerId}
+		}
+
+		if len(peer.Addresses) > 0 && !slices.Equal(peer.Addresses, booked.Addresses) {
+			booked.Addresses = peer.Addresses
+			changed = true
+		}
+
+		changed = changed || !ok
+		change(&booked)
+		book.peers[peer.PeerId] = booked
+	}
+
+	book.dirty = true
+	if !changed && time.Since(book.saved) < peerBookSaveInterval {
+		return nil
+	}
+
+	return book.save()
+}
+
+// connected records a successful Connect to the peer.
+func (book *peerBook) connected(peer BookedPeer) error {
+	now := time.Now()
+	return book.update(func(booked *BookedPeer) { booked.LastSuccess = now }, peer)
+}
+
+// seen records the peers listed by Peers.
+func (book *peerBook) seen(peers ...BookedPeer) error {
+	now := time.Now()
+	return book.update(func(booked *BookedPeer) { booked.LastSeen = now }, peers...)
+}
+
+// forget removes the peer from the peer book.
+func (book *peerBook) forget(peerId string) error {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	if _, ok := book.peers[peerId]; !ok {
+		return nil
+	}
+
+	delete(book.peers, peerId)
+	return book.save()
+}
+
+// flush writes the changes not written yet.
+func (book *peerBook) flush() error {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	if !book.dirty {
+		return nil
+	}
+
+	return book.save()
+}
+
+// recent returns up to n peers, the ones connected with Connect first,
+// by decreasing time.
+func (book *peerBook) recent(n int) []BookedPeer {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	peers := book.sorted()
+	if n >= 0 && len(peers) > n {
+		peers = peers[:n]
+	}
+
+	return peers
+}
+
+// sorted returns the peers, the most recent first.
+// The caller must hold the lock.
+func (book *peerBook) sorted() []BookedPeer {
+	peers := make([]BookedPeer, 0, len(book.peers))
+	for _, peer := range book.peers {
+		peers = append(peers, peer)
+	}
+
+	slices.SortFunc(peers, moreRecent)
+	return peers
+}
+
+// save writes the peer book file, replacing it atomically. The least
+// recent peers over maxBookedPeers are dropped.
+// The caller must hold the lock.
+func (book *peerBook) save() error {
+	peers := book.sorted()
+	if len(peers) > maxBookedPeers {
+		for _, peer := range peers[maxBookedPeers:] {
+			delete(book.peers, peer.PeerId)
+		}
+
+		peers = peers[:maxBookedPeers]
+	}
+
+	data, err := json.MarshalIndent(peers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the peer book: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(book.path), 0o755); err != nil {
+		return fmt.Errorf("failed to write the peer book: %w", err)
+	}
+
+	tmp := book.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write the peer book: %w", err)
+	}
+
+	if err := os.Rename(tmp, book.path); err != nil {
+		return fmt.Errorf("failed to write the peer book: %w", err)
+	}
+
+	book.saved = time.Now()
+	book.dirty = false
+
+	return nil
+}
+
+// PeerBook returns the peers of the peer book, the ones connected with
+// Connect first, by decreasing time. The peer book is fed by Connect and
+// Peers, and Start reconnects to its most recent peers.
+func (node *CodexNode) PeerBook() []BookedPeer {
+	if node.peerBook == nil {
+		return nil
+	}
+
+	return node.peerBook.recent(-1)
+}
+
+// recordConnect adds the peer connected with Connect to the peer book.
+// The peer book is a best effort: failing to write it does not fail the
+// call feeding it.
+func (node *CodexNode) recordConnect(peer BookedPeer) {
+	if node.peerBook == nil || node.isDenied(peer.PeerId) {
+		return
+	}
+
+	node.peerBook.connected(peer)
+}
+
+// recordPeers adds the peers listed by Peers to the peer book.
+func (node *CodexNode) recordPeers(peers ...BookedPeer) {
+	if node.peerBook == nil || len(peers) == 0 {
+		return
+	}
+
+	node.peerBook.seen(peers...)
+}
+
+// setDenied records whether the peer is denied, a denied peer is removed
+// from the peer book and is not reconnected.
+func (node *CodexNode) setDenied(peerId string, denied bool) {
+	node.mu.Lock()
+	if denied {
+		node.denied[peerId] = struct{}{}
+	} else {
+		delete(node.denied, peerId)
+	}
+	node.mu.Unlock()
+
+	if denied && node.peerBook != nil {
+		node.peerBook.forget(peerId)
+	}
+}
+
+func (node *CodexNode) isDenied(peerId string) bool {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	_, ok := node.denied[peerId]
+	return ok
+}
+
+// startReconnect reconnects in the background to the most recent peers
+// of the peer book, the denied ones excluded. Each peer is retried with
+// an exponential backoff, until stopReconnect is called.
+func (node *CodexNode) startReconnect() {
+	if node.peerBook == nil || node.reconnectPeers < 0 {
+		return
+	}
+
+	n := node.reconnectPeers
+	if n == 0 {
+		n = DefaultReconnectPeers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	node.reconnectCancel = cancel
+
+	for _, peer := range node.peerBook.recent(-1) {
+		if n == 0 {
+			break
+		}
+
+		if node.isDenied(peer.PeerId) {
+			continue
+		}
+
+		n--
+		node.reconnectWg.Add(1)
+		go func() {
+			defer node.reconnectWg.Done()
+			node.reconnect(ctx, peer)
+		}()
+	}
+}
+
+// stopReconnect stops the reconnections, waits for the calls in flight
+// and writes the peer book changes not written yet.
+func (node *CodexNode) stopReconnect() {
+	if node.reconnectCancel != nil {
+		node.reconnectCancel()
+		node.reconnectCancel = nil
+	}
+
+	node.reconnectWg.Wait()
+
+	if node.peerBook != nil {
+		node.peerBook.flush()
+	}
+}
+
+func (node *CodexNode) reconnect(ctx context.Context, peer BookedPeer) {
+	backoff := reconnectBackoff
+
+	for attempt := 0; attempt < reconnectAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(2*backoff, reconnectMaxBackoff)
+		}
+
+		// The peer may be denied while it is retried
+		if ctx.Err() != nil || node.isDenied(peer.PeerId) {
+			return
+		}
+
+		err := node.Connect(peer.PeerId, peer.Addresses)
+		if err == nil || errors.Is(err, ErrInvalidState) {
+			return
+		}
+	}
+}
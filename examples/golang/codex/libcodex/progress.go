@@ -0,0 +1,164 @@
+package libcodex
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// ErrCallbackPanic is returned when a user callback panics.
+var ErrCallbackPanic = errors.New("panic in callback")
+
+// safeCallback calls f and returns the panic it raised as an error.
+func safeCallback(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrCallbackPanic, r)
+		}
+	}()
+
+	f()
+	return nil
+}
+
+// progressEvent is a RET_PROGRESS callback of the Codex node.
+type progressEvent struct {
+	bytes int
+	chunk []byte
+}
+
+// progressQueue delivers the progress events received on the libcodex
+// thread to a handler running in a goroutine, so that the handler cannot
+// stall the Codex node. push never blocks, so the handler can call the node.
+//
+// In buffered mode, the events are queued in order. Once `buffer` events
+// are queued, the next ones are merged into the last one, their bytes
+// summed, unless the chunks must be delivered: the queue grows instead.
+// In coalescing mode, the bytes of the events received while the handler
+// runs are summed and delivered in a single call, without the chunks.
+//
+// After a panic of the handler, the next events are dropped and close
+// returns the panic as an error.
+type progressQueue struct {
+	handler     func(bytes int, chunk []byte)
+	coalesce    bool
+	canCoalesce bool
+	buffer      int
+
+	mu     sync.Mutex
+	events []progressEvent
+	closed bool
+	wake   chan struct{}
+
+	// Closed when the goroutine exits
+	stopped chan struct{}
+
+	failed atomic.Bool
+	err    error
+}
+
+// newProgressQueue starts the goroutine delivering the events to handler.
+// canCoalesce is false when the chunks must be delivered to the handler.
+func newProgressQueue(options codex.ProgressOptions, canCoalesce bool, handler func(bytes int, chunk []byte)) *progressQueue {
+	q := &progressQueue{
+		handler:     handler,
+		coalesce:    options.Coalesce && canCoalesce,
+		canCoalesce: canCoalesce,
+		buffer:      options.BufferOrDefault(),
+		wake:        make(chan struct{}, 1),
+		stopped:     make(chan struct{}),
+	}
+
+	if q.coalesce {
+		q.buffer = 1
+	}
+
+	go q.run()
+
+	return q
+}
+
+// push queues an event, it is called from the libcodex thread.
+func (q *progressQueue) push(bytes int, chunk []byte) {
+	if q.failed.Load() {
+		return
+	}
+
+	q.mu.Lock()
+	if n := len(q.events); q.canCoalesce && n >= q.buffer {
+		q.events[n-1].bytes += bytes
+	} else {
+		if q.canCoalesce {
+			chunk = nil
+		}
+
+		q.events = append(q.events, progressEvent{bytes: bytes, chunk: chunk})
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *progressQueue) run() {
+	defer close(q.stopped)
+
+	for {
+		q.mu.Lock()
+		events := q.events
+		q.events = nil
+		closed := q.closed
+		q.mu.Unlock()
+
+		if len(events) == 0 {
+			if closed {
+				return
+			}
+
+			<-q.wake
+			continue
+		}
+
+		// Keep draining after a panic, deliver drops the events
+		for _, event := range events {
+			q.deliver(event)
+		}
+	}
+}
+
+func (q *progressQueue) deliver(event progressEvent) {
+	if q.err != nil || (event.bytes == 0 && event.chunk == nil) {
+		return
+	}
+
+	if err := safeCallback(func() { q.handler(event.bytes, event.chunk) }); err != nil {
+		q.err = fmt.Errorf("failed to deliver the progress: %w", err)
+		q.failed.Store(true)
+	}
+}
+
+// close waits for the queued events to be delivered and stops the goroutine.
+// It must be called once the call completed, when no event can be pushed
+// anymore. It returns the panic of the handler, if any. A nil queue does nothing.
+func (q *progressQueue) close() error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	<-q.stopped
+	return q.err
+}
@@ -0,0 +1,27 @@
+package libcodex
+
+import (
+	"context"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// WaitReady blocks until the started node meets the readiness conditions:
+// enough nodes in its DHT routing table and, if required, a SPR record.
+// Start returns before the node has discovered any peer, so the content
+// uploaded right after Start may not be discoverable by the network.
+//
+// It polls codex_debug every options.PollInterval and returns the
+// context error if the conditions do not hold before the context is done.
+func (node *CodexNode) WaitReady(ctx context.Context, options codex.ReadyOptions) error {
+	if err := node.ensureRunning("WaitReady"); err != nil {
+		return err
+	}
+
+	// Not a call in flight: Stop does not wait for the conditions, the
+	// calls checking them fail once the node is stopping
+	node.endCall()
+
+	_, err := codex.WaitReady(ctx, node, options)
+	return err
+}
@@ -0,0 +1,172 @@
+package libcodex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// sessions keeps track of the upload and download sessions
+// opened on the node, so that Shutdown can wait for them.
+type sessions struct {
+	// Upload session ids returned by UploadInit
+	uploads map[string]struct{}
+
+	// Cids of the download sessions created by DownloadInit
+	downloads map[string]struct{}
+
+	// Closed when the last session ends while the node is draining
+	idle chan struct{}
+}
+
+func (s *sessions) len() int {
+	return len(s.uploads) + len(s.downloads)
+}
+
+// addUploadSession records an upload session opened by UploadInit.
+func (node *CodexNode) addUploadSession(sessionId string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if node.sessions.uploads == nil {
+		node.sessions.uploads = map[string]struct{}{}
+	}
+
+	node.sessions.uploads[sessionId] = struct{}{}
+}
+
+// removeUploadSession forgets an upload session once it is finalized or cancelled.
+func (node *CodexNode) removeUploadSession(sessionId string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	delete(node.sessions.uploads, sessionId)
+	node.notifyIdle()
+}
+
+// addDownloadSession records a download session opened by DownloadInit.
+func (node *CodexNode) addDownloadSession(cid string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if node.sessions.downloads == nil {
+		node.sessions.downloads = map[string]struct{}{}
+	}
+
+	node.sessions.downloads[cid] = struct{}{}
+}
+
+// removeDownloadSession forgets a download session once it is completed or cancelled.
+func (node *CodexNode) removeDownloadSession(cid string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	delete(node.sessions.downloads, cid)
+	node.notifyIdle()
+}
+
+// notifyIdle wakes up Shutdown when the last session ends.
+// The caller must hold the lock.
+func (node *CodexNode) notifyIdle() {
+	if node.draining && node.sessions.idle != nil && node.sessions.len() == 0 {
+		close(node.sessions.idle)
+		node.sessions.idle = nil
+	}
+}
+
+// drain stops accepting new calls and returns a channel closed
+// when all the sessions are ended.
+func (node *CodexNode) drain() <-chan struct{} {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.draining = true
+
+	idle := make(chan struct{})
+	if node.sessions.len() == 0 {
+		close(idle)
+	} else {
+		node.sessions.idle = idle
+	}
+
+	return idle
+}
+
+// undrain accepts the new calls again, after a failed Shutdown.
+func (node *CodexNode) undrain() {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.draining = false
+	node.sessions.idle = nil
+}
+
+// pendingSessions returns the sessions which are still opened.
+func (node *CodexNode) pendingSessions() (uploads []string, downloads []string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	for sessionId := range node.sessions.uploads {
+		uploads = append(uploads, sessionId)
+	}
+
+	for cid := range node.sessions.downloads {
+		downloads = append(downloads, cid)
+	}
+
+	return uploads, downloads
+}
+
+// Shutdown gracefully stops the node:
+//   - it stops accepting new calls, they return ErrInvalidState,
+//   - it waits for the in-flight upload and download sessions to end,
+//     up to the context deadline,
+//   - it cancels the sessions which are still running, using
+//     codex_upload_cancel and codex_download_cancel,
+//   - it stops and destroys the node, like Close, which waits for the
+//     other calls in flight, like Exists or Connect, before freeing the node.
+//
+// If Close fails, the node accepts the new calls again, Shutdown can be
+// called again.
+//
+// If the context expires before the sessions end, Shutdown returns
+// the context error, joined to the errors of the cancellation if any.
+//
+// Note that DownloadStream sessions cannot be cancelled by the Codex
+// node: Shutdown waits for them to end, even after the context deadline.
+// UploadFile sessions are cancelled like the others.
+func (node *CodexNode) Shutdown(ctx context.Context) error {
+	if node.State() == StateDestroyed {
+		return nil
+	}
+
+	var errs []error
+
+	select {
+	case <-node.drain():
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+
+		uploads, downloads := node.pendingSessions()
+
+		for _, sessionId := range uploads {
+			if err := node.UploadCancel(sessionId); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cancel upload session %s: %w", sessionId, err))
+			}
+		}
+
+		for _, cid := range downloads {
+			if err := node.DownloadCancel(cid); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cancel download session %s: %w", cid, err))
+			}
+		}
+	}
+
+	if err := node.Close(); err != nil {
+		// The node was not stopped, it accepts the new calls again
+		node.undrain()
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
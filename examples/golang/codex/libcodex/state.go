@@ -0,0 +1,157 @@
+package libcodex
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// State is the lifecycle state of a CodexNode.
+//
+//	Created -> Starting -> Running -> Stopping -> Stopped -> Destroyed
+//	                 ^                               |
+//	                 +-------------------------------+
+type State int
+
+const (
+	StateCreated State = iota
+	StateStarting
+	StateRunning
+	StateStopping
+	StateStopped
+	StateDestroyed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateCreated:
+		return "created"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	case StateDestroyed:
+		return "destroyed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// ErrInvalidState is returned when an operation is not allowed
+// in the current state of the node, for example calling Start twice,
+// uploading before Start or destroying a running node.
+var ErrInvalidState = errors.New("invalid node state")
+
+// States in which the Codex node exists in the Nim thread and
+// can answer the info requests.
+var aliveStates = []State{StateCreated, StateStarting, StateRunning, StateStopping, StateStopped}
+
+// State returns the current lifecycle state of the node.
+func (node *CodexNode) State() State {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.state
+}
+
+// transition moves the node to the state `to` if the current state is one of `from`.
+// It returns the previous state so that the caller can restore it if the operation fails.
+// Once the state is changed, the new calls are checked against it, and transition
+// waits for the calls in flight, so the state does not change under a call.
+func (node *CodexNode) transition(op string, to State, from ...State) (State, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	previous := node.state
+	if !slices.Contains(from, previous) {
+		return previous, fmt.Errorf("%w: cannot %s a node which is %s", ErrInvalidState, op, previous)
+	}
+
+	node.state = to
+
+	for node.calls > 0 {
+		node.callsDone().Wait()
+	}
+
+	return previous, nil
+}
+
+// setState sets the state unconditionally, it is used to complete
+// or rollback a transition.
+func (node *CodexNode) setState(state State) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.state = state
+}
+
+// callsDone returns the condition signaled when the last call in flight ends.
+// The caller must hold the lock.
+func (node *CodexNode) callsDone() *sync.Cond {
+	if node.idle == nil {
+		node.idle = sync.NewCond(&node.mu)
+	}
+
+	return node.idle
+}
+
+// beginCall returns ErrInvalidState if the current state is not one of `allowed`,
+// or if the node is shutting down and `draining` is set. Otherwise the call is
+// in flight until endCall, which the caller must defer.
+func (node *CodexNode) beginCall(op string, draining bool, allowed ...State) error {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if !slices.Contains(allowed, node.state) {
+		return fmt.Errorf("%w: cannot call %s on a node which is %s", ErrInvalidState, op, node.state)
+	}
+
+	if draining && node.draining {
+		return fmt.Errorf("%w: cannot call %s on a node which is shutting down", ErrInvalidState, op)
+	}
+
+	node.calls++
+	return nil
+}
+
+// endCall ends a call started by one of the ensure functions.
+func (node *CodexNode) endCall() {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.calls--
+	if node.calls == 0 {
+		node.callsDone().Broadcast()
+	}
+}
+
+// ensureState returns ErrInvalidState if the current state is not one of `allowed`.
+// On success, the caller must defer endCall.
+func (node *CodexNode) ensureState(op string, allowed ...State) error {
+	return node.beginCall(op, false, allowed...)
+}
+
+// ensureRunning returns ErrInvalidState if the node is not started
+// or if it is shutting down. On success, the caller must defer endCall.
+func (node *CodexNode) ensureRunning(op string) error {
+	return node.beginCall(op, true, StateRunning)
+}
+
+// ensureSession returns ErrInvalidState if the node is not started.
+// Unlike ensureRunning, it accepts the calls made while the node is
+// shutting down, so that the in-flight sessions can complete.
+// On success, the caller must defer endCall.
+func (node *CodexNode) ensureSession(op string) error {
+	return node.ensureState(op, StateRunning)
+}
+
+// ensureAlive returns ErrInvalidState if the node is destroyed.
+// On success, the caller must defer endCall.
+func (node *CodexNode) ensureAlive(op string) error {
+	return node.ensureState(op, aliveStates...)
+}
@@ -0,0 +1,217 @@
+package libcodex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// readChunk is a chunk read by the read ahead goroutine of uploadPipelined.
+type readChunk struct {
+	data []byte
+	err  error
+}
+
+// maxEmptyReads is the number of consecutive reads returning no data and
+// no error after which the upload fails with io.ErrNoProgress, like bufio.
+const maxEmptyReads = 100
+
+// readAhead reads the chunks of r in a goroutine, each one in its own
+// buffer, up to `window` chunks ahead. The channel is closed at the end
+// of r or after a read error. Closing stop ends the goroutine early.
+func readAhead(r io.Reader, chunkSize int, window int, stop <-chan struct{}) <-chan readChunk {
+	chunks := make(chan readChunk, window)
+
+	send := func(chunk readChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	go func() {
+		defer close(chunks)
+
+		empty := 0
+		for {
+			buf := make([]byte, chunkSize)
+			n, err := r.Read(buf)
+
+			// The bytes read come before the error, io.EOF included
+			if n > 0 {
+				empty = 0
+				if !send(readChunk{data: buf[:n]}) {
+					return
+				}
+			}
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				send(readChunk{err: err})
+				return
+			}
+
+			if n == 0 {
+				if empty++; empty == maxEmptyReads {
+					send(readChunk{err: io.ErrNoProgress})
+					return
+				}
+			}
+		}
+	}()
+
+	return chunks
+}
+
+// uploadPipelined uploads the content of r to the session with up to
+// options.Concurrency chunks in flight: the next chunks are read while
+// the previous ones are stored by the Codex node. The chunks are sent
+// in the order they are read, the Codex node stores them in the same
+// order. The progress is reported in order, when each chunk is stored.
+//
+// On error, it waits for the chunks in flight before returning, the caller
+// cancels the session.
+func (node *CodexNode) uploadPipelined(sessionId string, options codex.UploadOptions, r io.Reader) error {
+	window := options.Concurrency
+
+	var size int64
+	if options.OnProgress != nil {
+		size = codex.ReaderSize(r)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	chunks := readAhead(r, options.ChunkSize.ValOrDefault(), window, stop)
+
+	type inflight struct {
+		bridge *bridgeCtx
+		n      int
+	}
+
+	var pending []inflight
+	total := 0
+
+	// completeOldest waits for the oldest chunk in flight
+	completeOldest := func() error {
+		c := pending[0]
+		pending = pending[1:]
+
+		_, err := c.bridge.wait()
+		c.bridge.free()
+		if err != nil {
+			return err
+		}
+
+		total += c.n
+		if options.OnProgress != nil {
+			err := safeCallback(func() {
+				options.OnProgress(c.n, total, codex.Percent(total, size), nil)
+			})
+
+			if err != nil {
+				return fmt.Errorf("failed to deliver the progress: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	// drain waits for all the chunks in flight, after an error
+	drain := func(err error) error {
+		for _, c := range pending {
+			_, waitErr := c.bridge.wait()
+			c.bridge.free()
+			err = errors.Join(err, waitErr)
+		}
+
+		pending = nil
+		return err
+	}
+
+	for chunk := range chunks {
+		if chunk.err != nil {
+			return drain(chunk.err)
+		}
+
+		if len(pending) == window {
+			if err := completeOldest(); err != nil {
+				return drain(err)
+			}
+		}
+
+		bridge, err := node.sendUploadChunk(sessionId, chunk.data)
+		if err != nil {
+			return drain(err)
+		}
+
+		pending = append(pending, inflight{bridge: bridge, n: len(chunk.data)})
+	}
+
+	for len(pending) > 0 {
+		if err := completeOldest(); err != nil {
+			return drain(err)
+		}
+	}
+
+	return nil
+}
+
+// UploadReaderResult uploads the data of r like UploadReader and
+// describes the uploaded content, see codex.UploadReaderResult.
+func (node *CodexNode) UploadReaderResult(options codex.UploadOptions, r io.Reader) (codex.UploadResult, error) {
+	return codex.UploadReaderResult(node, options, r)
+}
+
+// uploadedFile is the result of codex_upload_file_result.
+type uploadedFile struct {
+	Cid      string         `json:"cid"`
+	Manifest codex.Manifest `json:"manifest"`
+	Sha256   string         `json:"sha256"`
+}
+
+// UploadFileResult uploads the file located at options.Filepath and
+// describes the uploaded content, see codex.UploadResult.
+func (node *CodexNode) UploadFileResult(options codex.UploadOptions) (codex.UploadResult, error) {
+	return node.UploadFileResultContext(context.Background(), options)
+}
+
+// UploadFileResultContext is UploadFileContext returning an UploadResult.
+// Like UploadFile, the file is read by the Codex node, which computes its
+// SHA-256 meanwhile and returns the manifest stored by the finalization.
+func (node *CodexNode) UploadFileResultContext(ctx context.Context, options codex.UploadOptions) (codex.UploadResult, error) {
+	start := time.Now()
+
+	options, err := codex.SniffFileMimetype(options)
+	if err != nil {
+		return codex.UploadResult{}, err
+	}
+
+	sessionId, err := node.UploadInit(&options)
+	if err != nil {
+		return codex.UploadResult{}, err
+	}
+
+	resp, err := node.uploadFile(ctx, sessionId, options, true)
+	if err != nil {
+		return codex.UploadResult{}, err
+	}
+
+	var uploaded uploadedFile
+	if err := json.Unmarshal([]byte(resp), &uploaded); err != nil {
+		return codex.UploadResult{}, fmt.Errorf("failed to decode the upload result: %w", err)
+	}
+
+	uploaded.Manifest.Cid = uploaded.Cid
+	return codex.NewUploadResult(uploaded.Manifest, uploaded.Sha256, time.Since(start)), nil
+}
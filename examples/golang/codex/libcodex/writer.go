@@ -0,0 +1,280 @@
+package libcodex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// ErrUploadAborted is returned by the UploadWriter methods after Abort.
+var ErrUploadAborted = errors.New("upload aborted")
+
+// ErrUploadCancelled is returned by UploadFile when its session is
+// cancelled, with UploadCancel or with the context.
+var ErrUploadCancelled = errors.New("upload cancelled")
+
+// Contained in the errors returned by libcodex for the cancelled uploads
+const uploadCancelledMsg = "upload cancelled"
+
+func isUploadCancelled(err error) bool {
+	return strings.Contains(err.Error(), uploadCancelledMsg)
+}
+
+var _ io.WriteCloser = (*UploadWriter)(nil)
+
+// UploadWriter uploads the data written to it to an upload session.
+// The data is buffered and sent to the Codex node by chunks of
+// options.ChunkSize. Close sends the last chunk and finalizes the upload,
+// then Cid returns the CID of the content.
+//
+// The size of the content is unknown, the progress is reported with
+// codex.PercentUnknown.
+//
+// Write and Close must be called from a single goroutine, Abort can
+// be called from any goroutine.
+type UploadWriter struct {
+	node    *CodexNode
+	options codex.UploadOptions
+
+	buf   []byte
+	total int
+
+	mu sync.Mutex
+	// Empty until the session is opened, with the first chunk
+	// when the mimetype is sniffed
+	sessionId string
+	closed    bool
+	cid       string
+	err       error
+}
+
+// Create opens an upload session and returns a writer to it.
+// options.Filepath is the file name stored in the manifest,
+// it is used to detect the mimetype. When the mimetype cannot be
+// deduced from the options, it is detected from the first chunk, like
+// UploadReader does, and the session is opened with this chunk.
+//
+// Internally, it calls UploadInit, then the writer calls UploadChunk
+// for each chunk, UploadFinalize on Close and UploadCancel on Abort.
+func (node *CodexNode) Create(options codex.UploadOptions) (*UploadWriter, error) {
+	w := &UploadWriter{
+		node:    node,
+		options: options,
+		buf:     make([]byte, 0, options.ChunkSize.ValOrDefault()),
+	}
+
+	if !options.NeedsSniffing() {
+		if err := w.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// open opens the upload session, the mimetype is detected from the
+// buffered data if needed.
+func (w *UploadWriter) open() error {
+	options, _, err := codex.SniffMimetype(w.options, bytes.NewReader(w.buf))
+	if err != nil {
+		return err
+	}
+
+	sessionId, err := w.node.UploadInit(&options)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.options = options
+	w.sessionId = sessionId
+
+	// Aborted while the session was opened
+	if w.closed {
+		return errors.Join(w.err, w.node.UploadCancel(sessionId))
+	}
+
+	return nil
+}
+
+// SessionId returns the id of the upload session, empty until the
+// first chunk is sent when the mimetype is detected from it.
+func (w *UploadWriter) SessionId() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sessionId
+}
+
+// failure returns the error which ended the upload, if any.
+func (w *UploadWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil && w.closed {
+		return errors.New("failed to write: the upload writer is closed")
+	}
+
+	return w.err
+}
+
+// fail records the first error and cancels the session.
+func (w *UploadWriter) fail(err error) error {
+	w.mu.Lock()
+	if w.err != nil {
+		err = w.err
+		w.mu.Unlock()
+		return err
+	}
+
+	w.err = err
+	w.closed = true
+	sessionId := w.sessionId
+	w.mu.Unlock()
+
+	if sessionId == "" {
+		return err
+	}
+
+	if cancelErr := w.node.UploadCancel(sessionId); cancelErr != nil {
+		return fmt.Errorf("failed to upload chunk %v and failed to cancel upload session %v", err, cancelErr)
+	}
+
+	return err
+}
+
+// flush sends the buffered data as a chunk, opening the session first
+// if needed.
+func (w *UploadWriter) flush() error {
+	if w.SessionId() == "" {
+		if err := w.open(); err != nil {
+			return w.fail(err)
+		}
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	if err := w.node.UploadChunk(w.SessionId(), w.buf); err != nil {
+		return w.fail(err)
+	}
+
+	n := len(w.buf)
+	w.total += n
+	// libcodex copies the chunk, the buffer can be reused
+	w.buf = w.buf[:0]
+
+	if w.options.OnProgress != nil {
+		err := safeCallback(func() {
+			w.options.OnProgress(n, w.total, codex.PercentUnknown, nil)
+		})
+
+		if err != nil {
+			return w.fail(fmt.Errorf("failed to deliver the progress: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// Write buffers p and sends a chunk each time the buffer is full.
+// It returns the error of the upload after a failure or an Abort.
+func (w *UploadWriter) Write(p []byte) (int, error) {
+	if err := w.failure(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), cap(w.buf)-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close sends the buffered data and finalizes the upload.
+// Calling Close again returns the same result.
+func (w *UploadWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		err := w.err
+		w.mu.Unlock()
+		return err
+	}
+	w.mu.Unlock()
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	cid, err := w.node.UploadFinalize(w.SessionId())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Abort may have been called during the finalization
+	if w.closed {
+		return w.err
+	}
+
+	w.closed = true
+	w.cid = cid
+	w.err = err
+
+	return err
+}
+
+// Cid returns the CID of the uploaded content once Close succeeded,
+// an empty string otherwise.
+func (w *UploadWriter) Cid() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.cid
+}
+
+// Abort cancels the upload session. The next calls to Write and Close
+// return an error wrapping ErrUploadAborted and err, if it is not nil.
+// Abort does nothing if the upload is already closed.
+func (w *UploadWriter) Abort(err error) error {
+	abortErr := ErrUploadAborted
+	if err != nil {
+		abortErr = fmt.Errorf("%w: %w", ErrUploadAborted, err)
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+
+	w.closed = true
+	w.err = abortErr
+	sessionId := w.sessionId
+	w.mu.Unlock()
+
+	// Not opened yet, open cancels it
+	if sessionId == "" {
+		return nil
+	}
+
+	return w.node.UploadCancel(sessionId)
+}
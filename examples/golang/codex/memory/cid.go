@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// Codes of the multicodecs used by Codex, see
+// https://github.com/multiformats/multicodec/blob/master/table.csv
+const (
+	sha256Codec   = 0x12
+	manifestCodec = 0xcd01
+	datasetCodec  = 0xcd03
+	blockCodec    = 0xcd02
+	cidVersion    = 1
+
+	// Value of CIDv1 in the CidVersion enum of nim-libp2p,
+	// as written in the manifest.
+	manifestCidVersion = 2
+)
+
+// Keys appended to the hashes when building the merkle tree,
+// they must match ByteTreeKey in codex/merkletree/codex/codex.nim.
+const (
+	keyNone              = 0x0
+	keyBottomLayer       = 0x1
+	keyOdd               = 0x2
+	keyOddAndBottomLayer = 0x3
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func appendVarint(buf []byte, v uint64) []byte {
+	return binary.AppendUvarint(buf, v)
+}
+
+// cidBytes returns the binary representation of a CIDv1
+// using a sha2-256 multihash.
+func cidBytes(codec uint64, digest []byte) []byte {
+	buf := appendVarint(nil, cidVersion)
+	buf = appendVarint(buf, codec)
+	buf = appendVarint(buf, sha256Codec)
+	buf = appendVarint(buf, uint64(len(digest)))
+	return append(buf, digest...)
+}
+
+// cidString encodes a binary CID in base58btc, the default encoding
+// used by Codex.
+func cidString(cid []byte) string {
+	return "z" + base58(cid)
+}
+
+func base58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	n := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for i := 0; i < zeros; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
+
+func compress(x, y []byte, key byte) []byte {
+	h := sha256.New()
+	h.Write(x)
+	h.Write(y)
+	h.Write([]byte{key})
+	return h.Sum(nil)
+}
+
+// merkleRoot computes the root of the tree built from the leaves,
+// following merkleTreeWorker in codex/merkletree/merkletree.nim.
+func merkleRoot(leaves [][]byte) []byte {
+	zero := make([]byte, sha256.Size)
+	layer := leaves
+	bottom := true
+
+	for bottom || len(layer) > 1 {
+		half := len(layer) / 2
+		next := make([][]byte, 0, half+1)
+
+		key, oddKey := byte(keyNone), byte(keyOdd)
+		if bottom {
+			key, oddKey = keyBottomLayer, keyOddAndBottomLayer
+		}
+
+		for i := 0; i < half; i++ {
+			next = append(next, compress(layer[2*i], layer[2*i+1], key))
+		}
+
+		if len(layer)%2 == 1 {
+			next = append(next, compress(layer[len(layer)-1], zero, oddKey))
+		}
+
+		layer = next
+		bottom = false
+	}
+
+	return layer[0]
+}
+
+func appendProtoVarint(buf []byte, field int, v uint64) []byte {
+	buf = appendVarint(buf, uint64(field<<3))
+	return appendVarint(buf, v)
+}
+
+func appendProtoBytes(buf []byte, field int, v []byte) []byte {
+	buf = appendVarint(buf, uint64(field<<3|2))
+	buf = appendVarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+// encodeManifest encodes a non protected manifest,
+// following encode in codex/manifest/coders.nim.
+func encodeManifest(treeCid []byte, blockSize int, datasetSize int64, filename, mimetype string) []byte {
+	var header []byte
+	header = appendProtoBytes(header, 1, treeCid)
+	header = appendProtoVarint(header, 2, uint64(blockSize))
+	header = appendProtoVarint(header, 3, uint64(datasetSize))
+	header = appendProtoVarint(header, 4, blockCodec)
+	header = appendProtoVarint(header, 5, sha256Codec)
+	header = appendProtoVarint(header, 6, manifestCidVersion)
+
+	if filename != "" {
+		header = appendProtoBytes(header, 8, []byte(filename))
+	}
+
+	if mimetype != "" {
+		header = appendProtoBytes(header, 9, []byte(mimetype))
+	}
+
+	return appendProtoBytes(nil, 1, header)
+}
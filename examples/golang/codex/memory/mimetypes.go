@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Mimetypes of the common extensions, with the values of the Nim
+// std/mimetypes table used by the Codex node. The mimetype is part of
+// the manifest, so it must not depend on the mime.types of the system
+// like mime.TypeByExtension. The extensions mapped to several mimetypes
+// in the databases, like xml or mp3, are left out.
+var mimetypes = map[string]string{
+	"txt":      "text/plain",
+	"text":     "text/plain",
+	"log":      "text/plain",
+	"conf":     "text/plain",
+	"html":     "text/html",
+	"htm":      "text/html",
+	"css":      "text/css",
+	"csv":      "text/csv",
+	"md":       "text/markdown",
+	"markdown": "text/markdown",
+	"ics":      "text/calendar",
+	"vtt":      "text/vtt",
+	"json":     "application/json",
+	"js":       "application/javascript",
+	"mjs":      "application/javascript",
+	"pdf":      "application/pdf",
+	"zip":      "application/zip",
+	"gz":       "application/gzip",
+	"tar":      "application/x-tar",
+	"7z":       "application/x-7z-compressed",
+	"bz2":      "application/x-bzip2",
+	"wasm":     "application/wasm",
+	"epub":     "application/epub+zip",
+	"jar":      "application/java-archive",
+	"bin":      "application/octet-stream",
+	"iso":      "application/x-iso9660-image",
+	"doc":      "application/msword",
+	"xls":      "application/vnd.ms-excel",
+	"ppt":      "application/vnd.ms-powerpoint",
+	"docx":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xlsx":     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pptx":     "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"png":      "image/png",
+	"jpg":      "image/jpeg",
+	"jpeg":     "image/jpeg",
+	"gif":      "image/gif",
+	"svg":      "image/svg+xml",
+	"webp":     "image/webp",
+	"tif":      "image/tiff",
+	"tiff":     "image/tiff",
+	"mp4":      "video/mp4",
+	"webm":     "video/webm",
+	"mkv":      "video/x-matroska",
+	"avi":      "video/x-msvideo",
+	"mov":      "video/quicktime",
+	"mpeg":     "video/mpeg",
+	"ogg":      "audio/ogg",
+	"oga":      "audio/ogg",
+	"ttf":      "font/ttf",
+	"otf":      "font/otf",
+	"woff":     "font/woff",
+	"woff2":    "font/woff2",
+}
+
+// mimetypeOf returns the mimetype of the file from its extension, like
+// the Codex node, empty when the extension is unknown. The extension is
+// case insensitive and a hidden file, like .bashrc, has no extension.
+func mimetypeOf(filename string) string {
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	if ext == base {
+		return ""
+	}
+
+	return mimetypes[strings.ToLower(strings.TrimPrefix(ext, "."))]
+}
@@ -0,0 +1,417 @@
+// Package memory provides an in-memory Codex node for unit tests.
+//
+// The node keeps the uploaded datasets in memory and computes
+// the same CIDs as a real Codex node, so the values returned by
+// UploadReader can be compared with the ones of a real network.
+// It does not reach the network: Fetch and DownloadStream only
+// succeed for content uploaded on the same node.
+package memory
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+const (
+	Version = "memory"
+
+	// Default storage quota, identical to the Codex node default.
+	DefaultQuota = 20 * 1024 * 1024 * 1024
+)
+
+// Node implements the common node interface.
+var _ codex.Node = (*Node)(nil)
+
+type dataset struct {
+	manifest codex.Manifest
+	data     []byte
+	blocks   []string
+
+	// Size of the encoded manifest, stored as a block too
+	manifestSize int64
+}
+
+// storedBlock is a block stored once, whatever the number of
+// datasets referencing it, like in the Codex node store.
+type storedBlock struct {
+	refs int
+	size int64
+}
+
+type Node struct {
+	mu       sync.Mutex
+	peerId   string
+	started  bool
+	datasets map[string]*dataset
+
+	// Blocks of the datasets, by cid
+	blocks map[string]*storedBlock
+
+	// QuotaMaxBytes is the storage quota reported by Space
+	// and enforced by the uploads.
+	QuotaMaxBytes int64
+}
+
+// New creates an in-memory node with a random peer id.
+func New() *Node {
+	id := make([]byte, sha256.Size)
+	rand.Read(id)
+
+	return &Node{
+		peerId:        base58(append([]byte{sha256Codec, sha256.Size}, id...)),
+		datasets:      map[string]*dataset{},
+		blocks:        map[string]*storedBlock{},
+		QuotaMaxBytes: DefaultQuota,
+	}
+}
+
+// Start starts the node.
+func (node *Node) Start() error {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.started = true
+	return nil
+}
+
+// Stop stops the node.
+func (node *Node) Stop() error {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.started = false
+	return nil
+}
+
+// Destroy removes all the stored datasets.
+func (node *Node) Destroy() error {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if node.started {
+		return errors.New("failed to destroy the node: the node must be stopped")
+	}
+
+	node.datasets = map[string]*dataset{}
+	node.blocks = map[string]*storedBlock{}
+	return nil
+}
+
+func (node *Node) Version() (string, error) {
+	return Version, nil
+}
+
+func (node *Node) Revision() (string, error) {
+	return "", nil
+}
+
+// Repo returns an empty path, the data is not stored on disk.
+func (node *Node) Repo() (string, error) {
+	return "", nil
+}
+
+// Spr always fails, the node is not part of a network.
+func (node *Node) Spr() (string, error) {
+	return "", errors.New("failed to get SPR: no SPR record found")
+}
+
+func (node *Node) PeerId() (string, error) {
+	return node.peerId, nil
+}
+
+func (node *Node) Debug() (codex.DebugInfo, error) {
+	return codex.DebugInfo{
+		Id: node.peerId,
+		Table: codex.RoutingTable{
+			LocalNode: codex.DhtNode{PeerId: node.peerId, Seen: true},
+		},
+	}, nil
+}
+
+// Connect does nothing, the node is not part of a network.
+func (node *Node) Connect(peerId string, peerAddresses []string) error {
+	return nil
+}
+
+// UploadReader stores the data read from r and returns its CID.
+// The data is split into blocks of options.ChunkSize bytes, the last
+// one being padded with zeros, like in the Codex node store.
+func (node *Node) UploadReader(options codex.UploadOptions, r io.Reader) (string, error) {
+	options, r, err := codex.SniffMimetype(options, r)
+	if err != nil {
+		return "", err
+	}
+
+	blockSize := options.ChunkSize.ValOrDefault()
+
+	var size int64
+	if options.OnProgress != nil {
+		size = codex.ReaderSize(r)
+	}
+
+	var data bytes.Buffer
+	var leaves [][]byte
+	var blocks []string
+	buf := make([]byte, blockSize)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+
+		data.Write(buf[:n])
+
+		// Pad the last block
+		clear(buf[n:])
+		digest := sha256.Sum256(buf)
+		leaves = append(leaves, digest[:])
+		blocks = append(blocks, cidString(cidBytes(blockCodec, digest[:])))
+
+		if options.OnProgress != nil {
+			options.OnProgress(n, data.Len(), codex.Percent(data.Len(), size), nil)
+		}
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	if len(leaves) == 0 {
+		return "", errors.New("failed to finalize the upload session: Empty leaves")
+	}
+
+	root := merkleRoot(leaves)
+	treeCid := cidBytes(datasetCodec, root)
+
+	filename := options.Filename
+	if filename == "" && options.Filepath != "" {
+		filename = filepath.Base(options.Filepath)
+	}
+
+	mimetype := options.Mimetype
+	if mimetype == "" && filename != "" {
+		mimetype = mimetypeOf(filename)
+	}
+
+	encoded := encodeManifest(treeCid, blockSize, int64(data.Len()), filename, mimetype)
+	digest := sha256.Sum256(encoded)
+	cid := cidString(cidBytes(manifestCodec, digest[:]))
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if _, ok := node.datasets[cid]; ok {
+		return cid, nil
+	}
+
+	// The blocks already stored do not use more space
+	size = int64(len(encoded))
+	added := map[string]bool{}
+	for _, block := range blocks {
+		if node.blocks[block] == nil && !added[block] {
+			added[block] = true
+			size += int64(blockSize)
+		}
+	}
+
+	if node.usedBytes()+size > node.QuotaMaxBytes {
+		return "", errors.New("failed to upload: not enough storage quota")
+	}
+
+	node.datasets[cid] = &dataset{
+		manifest: codex.Manifest{
+			Cid:         cid,
+			TreeCid:     cidString(treeCid),
+			DatasetSize: int64(data.Len()),
+			BlockSize:   blockSize,
+			Filename:    filename,
+			Mimetype:    mimetype,
+		},
+		data:         data.Bytes(),
+		blocks:       blocks,
+		manifestSize: int64(len(encoded)),
+	}
+
+	for _, block := range blocks {
+		if node.blocks[block] == nil {
+			node.blocks[block] = &storedBlock{size: int64(blockSize)}
+		}
+
+		node.blocks[block].refs++
+	}
+
+	return cid, nil
+}
+
+// UploadFile stores the file located at options.Filepath.
+func (node *Node) UploadFile(options codex.UploadOptions) (string, error) {
+	file, err := os.Open(options.Filepath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return node.UploadReader(options, file)
+}
+
+// DownloadStream writes the content identified by cid to options.Writer
+// and / or options.Filepath. The content must have been uploaded on this node.
+func (node *Node) DownloadStream(cid string, options codex.DownloadStreamOptions) error {
+	node.mu.Lock()
+	d, ok := node.datasets[cid]
+	node.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("failed to init the download: %s not found", cid)
+	}
+
+	writers := []io.Writer{}
+	if options.Writer != nil {
+		writers = append(writers, options.Writer)
+	}
+
+	if options.Filepath != "" {
+		file, err := os.Create(options.Filepath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		writers = append(writers, file)
+	}
+
+	size := options.DatasetSize
+	if options.DatasetSizeAuto {
+		size = len(d.data)
+	}
+
+	w := io.MultiWriter(writers...)
+	chunkSize := options.ChunkSize.ValOrDefault()
+	total := 0
+
+	for total < len(d.data) {
+		n := min(chunkSize, len(d.data)-total)
+
+		_, err := w.Write(d.data[total : total+n])
+		total += n
+
+		if options.OnProgress != nil {
+			options.OnProgress(n, total, codex.Percent(total, int64(size)), err)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (node *Node) DownloadManifest(cid string) (codex.Manifest, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	d, ok := node.datasets[cid]
+	if !ok {
+		return codex.Manifest{Cid: cid}, fmt.Errorf("failed to fetch manifest: %s not found", cid)
+	}
+
+	return d.manifest, nil
+}
+
+func (node *Node) List() ([]codex.Manifest, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	manifests := make([]codex.Manifest, 0, len(node.datasets))
+	for _, d := range node.datasets {
+		manifests = append(manifests, d.manifest)
+	}
+
+	return manifests, nil
+}
+
+func (node *Node) Space() (codex.Space, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return codex.Space{
+		TotalBlocks:    len(node.blocks) + len(node.datasets),
+		QuotaMaxBytes:  node.QuotaMaxBytes,
+		QuotaUsedBytes: node.usedBytes(),
+	}, nil
+}
+
+// Delete removes a dataset and the blocks that are not used
+// by another dataset. Deleting an unknown cid does nothing.
+func (node *Node) Delete(cid string) error {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	d, ok := node.datasets[cid]
+	if !ok {
+		return nil
+	}
+
+	for _, block := range d.blocks {
+		node.blocks[block].refs--
+		if node.blocks[block].refs <= 0 {
+			delete(node.blocks, block)
+		}
+	}
+
+	delete(node.datasets, cid)
+	return nil
+}
+
+// Fetch returns the manifest of a dataset already stored on this node.
+func (node *Node) Fetch(cid string) (codex.Manifest, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	d, ok := node.datasets[cid]
+	if !ok {
+		return codex.Manifest{Cid: cid}, fmt.Errorf("failed to fetch the data: %s not found", cid)
+	}
+
+	return d.manifest, nil
+}
+
+// Exists checks if the cid is a dataset or a block stored on this node.
+func (node *Node) Exists(cid string) (bool, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	_, ok := node.datasets[cid]
+	return ok || node.blocks[cid] != nil, nil
+}
+
+// usedBytes returns the space used by the manifests and the blocks,
+// each block being counted once.
+// The caller must hold the lock.
+func (node *Node) usedBytes() int64 {
+	var used int64
+	for _, d := range node.datasets {
+		used += d.manifestSize
+	}
+
+	for _, block := range node.blocks {
+		used += block.size
+	}
+
+	return used
+}
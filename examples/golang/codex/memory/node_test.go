@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+// CID returned by a Codex node when uploading "Hello World!" as hello.txt
+// with the default block size.
+const helloWorldCid = "zDvZRwzmAkhzDRPH5EW242gJBNZ2T7aoH2v1fVH66FxXL4kSbvyM"
+
+func TestUploadComputesCodexCid(t *testing.T) {
+	node := New()
+
+	cid, err := node.UploadReader(codex.UploadOptions{Filepath: "hello.txt"}, bytes.NewBufferString("Hello World!"))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if cid != helloWorldCid {
+		t.Fatalf("expected cid %s, got %s", helloWorldCid, cid)
+	}
+
+	manifest, err := node.DownloadManifest(cid)
+	if err != nil {
+		t.Fatalf("manifest failed: %v", err)
+	}
+
+	if manifest.DatasetSize != 12 || manifest.Filename != "hello.txt" || manifest.Mimetype != "text/plain" {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+}
+
+// Tree CID returned by a Codex node when uploading "some file contents"
+// with the default block size, see tests/integration/5_minutes/testupdownload.nim.
+const someFileContentsTreeCid = "zDzSvJTezk7bJNQqFq8k1iHXY84psNuUfZVusA5bBQQUSuyzDSVL"
+
+func TestUploadComputesCodexTreeCid(t *testing.T) {
+	node := New()
+
+	cid, err := node.UploadReader(codex.UploadOptions{}, bytes.NewBufferString("some file contents"))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	manifest, err := node.DownloadManifest(cid)
+	if err != nil {
+		t.Fatalf("manifest failed: %v", err)
+	}
+
+	if manifest.TreeCid != someFileContentsTreeCid {
+		t.Fatalf("expected tree cid %s, got %s", someFileContentsTreeCid, manifest.TreeCid)
+	}
+}
+
+// The CIDs of the datasets of several blocks, with an odd number of leaves
+// on the bottom layer and, for 5 blocks, on the layer above, so that
+// KeyOddAndBottomLayer and KeyOdd are both used. They are computed with a
+// separate implementation of merkleTreeWorker (codex/merkletree/merkletree.nim)
+// and Manifest.encode (codex/manifest/coders.nim), which gives helloWorldCid
+// and someFileContentsTreeCid above.
+func TestUploadComputesCodexCidOfOddBlocks(t *testing.T) {
+	tests := []struct {
+		size    int
+		blocks  int
+		cid     string
+		treeCid string
+	}{
+		{2545, 3, "zDvZRwzm3FaTSN724QJZU2HMCZVtNqUZneCfFM8kKWR4jdQEWpgi", "zDzSvJTf8TSsvDR8JnHvdMpUqQeNvoRYGEpLhPa43nXBCTTnpxRH"},
+		{4595, 5, "zDvZRwzmCdeVWE7e1EUhJAnrYnsGtpeF7taX9Qn8C1cWTXUSBDTB", "zDzSvJTf5G4MFSsBwMZNFVjPoTMbFenEVtpzDXjWViUUc8PQ2bTu"},
+	}
+
+	for _, test := range tests {
+		node := New()
+		data := bytes.Repeat([]byte("codex"), test.size/5)
+
+		var blocks int
+		options := codex.UploadOptions{
+			Filename:  "data.bin",
+			Mimetype:  "application/octet-stream",
+			ChunkSize: 1024,
+			OnProgress: func(read, total int, percent float64, err error) {
+				blocks++
+			},
+		}
+
+		cid, err := node.UploadReader(options, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+
+		if blocks != test.blocks {
+			t.Fatalf("expected %d blocks, got %d", test.blocks, blocks)
+		}
+
+		if cid != test.cid {
+			t.Fatalf("expected cid %s for %d blocks, got %s", test.cid, test.blocks, cid)
+		}
+
+		manifest, err := node.DownloadManifest(cid)
+		if err != nil {
+			t.Fatalf("manifest failed: %v", err)
+		}
+
+		if manifest.TreeCid != test.treeCid || manifest.DatasetSize != int64(test.size) || manifest.BlockSize != 1024 {
+			t.Fatalf("unexpected manifest %+v", manifest)
+		}
+	}
+}
+
+func TestDownloadAndDelete(t *testing.T) {
+	node := New()
+	data := bytes.Repeat([]byte("codex"), 50000)
+
+	cid, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := node.DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf}); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("downloaded data does not match")
+	}
+
+	if err := node.Delete(cid); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	exists, err := node.Exists(cid)
+	if err != nil || exists {
+		t.Fatalf("expected the dataset to be deleted, exists=%v err=%v", exists, err)
+	}
+
+	space, _ := node.Space()
+	if space.TotalBlocks != 0 || space.QuotaUsedBytes != 0 {
+		t.Fatalf("expected empty storage, got %+v", space)
+	}
+}
+
+func TestSpaceCountsSharedBlocksOnce(t *testing.T) {
+	node := New()
+	data := bytes.Repeat([]byte("codex"), 50000)
+
+	first, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024, Filename: "a.bin"}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	space, _ := node.Space()
+
+	// Another manifest for the same blocks
+	second, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024, Filename: "b.bin"}, bytes.NewReader(data))
+	if err != nil || second == first {
+		t.Fatalf("expected another dataset, got %s, err=%v", second, err)
+	}
+
+	shared, _ := node.Space()
+	if shared.QuotaUsedBytes-space.QuotaUsedBytes >= 1024 {
+		t.Fatalf("expected the blocks to be counted once, used %d then %d", space.QuotaUsedBytes, shared.QuotaUsedBytes)
+	}
+
+	if err := node.Delete(first); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	if exists, _ := node.Exists(second); !exists {
+		t.Fatalf("expected the other dataset to be kept")
+	}
+}
+
+func TestMimetypeOf(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"hello.txt":      "text/plain",
+		"photo.JPG":      "image/jpeg",
+		"archive.tar.gz": "application/gzip",
+		".bashrc":        "",
+		"noextension":    "",
+		"unknown.zzz":    "",
+	} {
+		if mimetype := mimetypeOf(filename); mimetype != expected {
+			t.Fatalf("expected %q for %s, got %q", expected, filename, mimetype)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package codex defines the common API shared by every Codex node
+// implementation available to Go programs:
+//
+//   - libcodex: the embedded node running inside the process through cgo.
+//   - rest: a client talking to a remote node through its REST API.
+//   - memory: an in-memory fake, computing real CIDs, for unit tests.
+//
+// Application code should depend on the Node interface so that the
+// implementation can be swapped without building libcodex.so.
+package codex
+
+import "io"
+
+// Node is the set of operations available on a Codex node,
+// whatever the way it is reached.
+type Node interface {
+	// Start starts the node.
+	Start() error
+
+	// Stop stops the node.
+	Stop() error
+
+	// Destroy frees all the resources held by the node.
+	// The node must be stopped before calling this method.
+	Destroy() error
+
+	// Version returns the version of the node.
+	Version() (string, error)
+
+	// Revision returns the git revision of the node.
+	Revision() (string, error)
+
+	// Repo returns the path of the data dir folder.
+	Repo() (string, error)
+
+	// Spr returns the signed peer record of the node.
+	Spr() (string, error)
+
+	// PeerId returns the libp2p peer id of the node.
+	PeerId() (string, error)
+
+	// Debug returns information about the node and its DHT routing table.
+	Debug() (DebugInfo, error)
+
+	// Connect connects to a peer. If no address is provided,
+	// the peer is looked up in the DHT.
+	Connect(peerId string, peerAddresses []string) error
+
+	// UploadReader uploads data from an io.Reader and returns its CID.
+	UploadReader(options UploadOptions, r io.Reader) (string, error)
+
+	// UploadFile uploads the file located at options.Filepath and returns its CID.
+	UploadFile(options UploadOptions) (string, error)
+
+	// DownloadStream downloads the content identified by cid
+	// and writes it to options.Writer and / or options.Filepath.
+	DownloadStream(cid string, options DownloadStreamOptions) error
+
+	// DownloadManifest retrieves the manifest of the content identified by cid.
+	DownloadManifest(cid string) (Manifest, error)
+
+	// List returns the manifests stored by the node.
+	List() ([]Manifest, error)
+
+	// Space returns the storage usage of the node.
+	Space() (Space, error)
+
+	// Delete deletes a single block or an entire dataset from the node.
+	Delete(cid string) error
+
+	// Fetch starts downloading the dataset identified by cid from the network
+	// to the node, in the background, and returns its manifest.
+	Fetch(cid string) (Manifest, error)
+
+	// Exists checks if the cid is available in the local store.
+	Exists(cid string) (bool, error)
+}
@@ -0,0 +1,538 @@
+// Package pin decides which datasets a Codex node keeps.
+//
+// The Manager persists a pin set in the data dir of the node. Reconcile,
+// called periodically by Run, drops the expired pins, fetches the blocks of
+// the pinned datasets missing from the node and, when the used storage crosses the
+// high-water mark, deletes the unpinned datasets, least recently used first,
+// until it goes below the low-water mark:
+//
+//	manager, _ := pin.New(node, pin.Options{})
+//	manager.Pin(pin.Pin{Cid: cid, Labels: map[string]string{"app": "backup"}})
+//	go manager.Run(ctx)
+//
+// The manager only uses the calls of codex.Node, so it works with every
+// node implementation.
+package pin
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+const (
+	// DefaultFilename is the name of the pin set file in the data dir.
+	DefaultFilename = "pins.json"
+
+	// DefaultHighWaterMark is the fraction of the quota above which
+	// the unpinned datasets are evicted.
+	DefaultHighWaterMark = 0.9
+
+	// DefaultLowWaterMark is the fraction of the quota below which
+	// the eviction stops.
+	DefaultLowWaterMark = 0.8
+
+	// DefaultInterval is the interval between two reconciliations of Run.
+	DefaultInterval = time.Minute
+)
+
+// Pin is a dataset kept by the node.
+type Pin struct {
+	Cid string `json:"cid"`
+
+	// Labels are free key-value pairs describing the pin.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Priority orders the fetch of the missing datasets,
+	// the highest priority first.
+	Priority int `json:"priority"`
+
+	// Expiry is the time after which the dataset is not pinned anymore.
+	// The zero value never expires.
+	Expiry time.Time `json:"expiry"`
+
+	// CreatedAt is the time of the first Pin call for the dataset.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Expired reports whether the pin is expired at the given time.
+func (p Pin) Expired(now time.Time) bool {
+	return !p.Expiry.IsZero() && !now.Before(p.Expiry)
+}
+
+// Options configures the Manager.
+type Options struct {
+	// Path is the pin set file. Default is DefaultFilename in the data
+	// dir returned by node.Repo.
+	Path string
+
+	// HighWaterMark is the fraction of QuotaMaxBytes above which
+	// the unpinned datasets are evicted. Default is DefaultHighWaterMark.
+	HighWaterMark float64
+
+	// LowWaterMark is the fraction of QuotaMaxBytes below which the
+	// eviction stops. Default is DefaultLowWaterMark.
+	LowWaterMark float64
+
+	// Interval is the interval between two reconciliations of Run.
+	// Default is DefaultInterval.
+	Interval time.Duration
+
+	// OnError receives the errors of the reconciliations of Run.
+	OnError func(error)
+}
+
+func (o Options) highWaterMark() float64 {
+	if o.HighWaterMark <= 0 {
+		return DefaultHighWaterMark
+	}
+
+	return o.HighWaterMark
+}
+
+func (o Options) lowWaterMark() float64 {
+	if o.LowWaterMark <= 0 {
+		return min(DefaultLowWaterMark, o.highWaterMark())
+	}
+
+	return o.LowWaterMark
+}
+
+func (o Options) interval() time.Duration {
+	if o.Interval <= 0 {
+		return DefaultInterval
+	}
+
+	return o.Interval
+}
+
+// Report describes the changes made by a reconciliation.
+type Report struct {
+	// Expired are the pins dropped because they expired.
+	Expired []string
+
+	// Fetched are the pinned datasets missing from the node
+	// which were fetched. The blocks missing from the other
+	// pinned datasets are fetched too, without being reported.
+	Fetched []string
+
+	// Evicted are the unpinned datasets deleted from the node.
+	Evicted []string
+
+	// Space is the storage usage at the end of the reconciliation.
+	Space codex.Space
+}
+
+// state is the content of the pin set file.
+type state struct {
+	Pins []Pin `json:"pins"`
+
+	// LastUsed is the last use of the datasets, pinned or not.
+	LastUsed map[string]time.Time `json:"lastUsed"`
+}
+
+// Manager keeps the pinned datasets on the node and evicts the others
+// when the storage is full. Its methods can be called concurrently.
+type Manager struct {
+	node    codex.Node
+	options Options
+	path    string
+	now     func() time.Time
+
+	mu       sync.Mutex
+	pins     map[string]Pin
+	lastUsed map[string]time.Time
+
+	// Pinned datasets fetched since the manager was created. The node
+	// fetches their missing blocks in the background, fetching them
+	// again would start a duplicate download of the whole dataset.
+	fetched map[string]struct{}
+
+	// Serializes the reconciliations
+	reconcileMu sync.Mutex
+}
+
+// New loads the pin set of the node, if any.
+func New(node codex.Node, options Options) (*Manager, error) {
+	if options.lowWaterMark() > options.highWaterMark() {
+		return nil, fmt.Errorf("the low-water mark %v is above the high-water mark %v", options.lowWaterMark(), options.highWaterMark())
+	}
+
+	path := options.Path
+	if path == "" {
+		repo, err := node.Repo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the data dir: %w", err)
+		}
+
+		if repo == "" {
+			return nil, errors.New("the node has no data dir, set the path of the pin set")
+		}
+
+		path = filepath.Join(repo, DefaultFilename)
+	}
+
+	m := &Manager{
+		node:     node,
+		options:  options,
+		path:     path,
+		now:      time.Now,
+		pins:     map[string]Pin{},
+		lastUsed: map[string]time.Time{},
+		fetched:  map[string]struct{}{},
+	}
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// load reads the pin set file, a missing file is an empty pin set.
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read the pin set: %w", err)
+	}
+
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to decode the pin set %s: %w", m.path, err)
+	}
+
+	for _, p := range s.Pins {
+		m.pins[p.Cid] = p
+	}
+
+	for cid, t := range s.LastUsed {
+		m.lastUsed[cid] = t
+	}
+
+	return nil
+}
+
+// save writes the pin set file, replacing it atomically.
+// The caller must hold the lock.
+func (m *Manager) save() error {
+	s := state{Pins: m.sortedPins(), LastUsed: m.lastUsed}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the pin set: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return fmt.Errorf("failed to write the pin set: %w", err)
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write the pin set: %w", err)
+	}
+
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to write the pin set: %w", err)
+	}
+
+	return nil
+}
+
+// sortedPins returns the pins by decreasing priority, then by cid.
+// The caller must hold the lock.
+func (m *Manager) sortedPins() []Pin {
+	pins := make([]Pin, 0, len(m.pins))
+	for _, p := range m.pins {
+		pins = append(pins, p)
+	}
+
+	slices.SortFunc(pins, func(a, b Pin) int {
+		return cmp.Or(cmp.Compare(b.Priority, a.Priority), cmp.Compare(a.Cid, b.Cid))
+	})
+
+	return pins
+}
+
+// Pin adds the dataset to the pin set, or replaces its pin, and fetches
+// its blocks missing from the node. The pin is saved even if the fetch
+// fails, the next reconciliation retries it.
+func (m *Manager) Pin(p Pin) error {
+	if p.Cid == "" {
+		return errors.New("failed to pin: empty cid")
+	}
+
+	m.mu.Lock()
+	if existing, ok := m.pins[p.Cid]; ok {
+		p.CreatedAt = existing.CreatedAt
+	} else {
+		p.CreatedAt = m.now()
+	}
+
+	m.pins[p.Cid] = p
+	m.lastUsed[p.Cid] = m.now()
+	err := m.save()
+	m.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = m.ensure(p.Cid)
+	return err
+}
+
+// Unpin removes the dataset from the pin set. The dataset stays on the
+// node until it is evicted. Unpinning a dataset not pinned does nothing.
+func (m *Manager) Unpin(cid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pins[cid]; !ok {
+		return nil
+	}
+
+	delete(m.pins, cid)
+	delete(m.fetched, cid)
+	return m.save()
+}
+
+// Get returns the pin of the dataset, if it is pinned.
+func (m *Manager) Get(cid string) (Pin, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.pins[cid]
+	return p, ok
+}
+
+// Pins returns the pins by decreasing priority.
+func (m *Manager) Pins() []Pin {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedPins()
+}
+
+// Touch records a use of the dataset, for instance a download, so that
+// it is evicted after the datasets used less recently.
+func (m *Manager) Touch(cid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastUsed[cid] = m.now()
+	return m.save()
+}
+
+// ensure fetches the dataset, so that the blocks missing from the node are
+// downloaded. The manifest alone tells nothing about the blocks, so a
+// dataset is fetched once even if its manifest is stored, for instance
+// after a restart, then again only when its manifest goes missing.
+// It returns true if the manifest was missing from the node.
+func (m *Manager) ensure(cid string) (bool, error) {
+	exists, err := m.node.Exists(cid)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the pinned dataset %s: %w", cid, err)
+	}
+
+	m.mu.Lock()
+	_, fetched := m.fetched[cid]
+	m.mu.Unlock()
+
+	if exists && fetched {
+		return false, nil
+	}
+
+	if _, err := m.node.Fetch(cid); err != nil {
+		return false, fmt.Errorf("failed to fetch the pinned dataset %s: %w", cid, err)
+	}
+
+	m.mu.Lock()
+	// Unpinned meanwhile
+	if _, ok := m.pins[cid]; ok {
+		m.fetched[cid] = struct{}{}
+	}
+	m.mu.Unlock()
+
+	return !exists, nil
+}
+
+// Reconcile drops the expired pins, fetches the blocks of the pinned
+// datasets missing from the node, then evicts the unpinned datasets if the storage is
+// above the high-water mark. It continues after the errors of a dataset
+// and returns them joined with the report.
+func (m *Manager) Reconcile() (Report, error) {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	var report Report
+	var errs []error
+
+	m.mu.Lock()
+	now := m.now()
+	for cid, p := range m.pins {
+		if p.Expired(now) {
+			delete(m.pins, cid)
+			report.Expired = append(report.Expired, cid)
+		}
+	}
+
+	if len(report.Expired) > 0 {
+		if err := m.save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	pins := m.sortedPins()
+	m.mu.Unlock()
+
+	for _, p := range pins {
+		fetched, err := m.ensure(p.Cid)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if fetched {
+			report.Fetched = append(report.Fetched, p.Cid)
+		}
+	}
+
+	space, evicted, err := m.evict()
+	report.Space = space
+	report.Evicted = evicted
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	slices.Sort(report.Expired)
+
+	return report, errors.Join(errs...)
+}
+
+// evict deletes the unpinned datasets, least recently used first, while
+// the used storage is above the low-water mark, once it crossed the
+// high-water mark.
+func (m *Manager) evict() (codex.Space, []string, error) {
+	space, err := m.node.Space()
+	if err != nil {
+		return space, nil, fmt.Errorf("failed to get the storage usage: %w", err)
+	}
+
+	high := int64(float64(space.QuotaMaxBytes) * m.options.highWaterMark())
+	low := int64(float64(space.QuotaMaxBytes) * m.options.lowWaterMark())
+
+	if space.QuotaUsedBytes <= high {
+		return space, nil, nil
+	}
+
+	manifests, err := m.node.List()
+	if err != nil {
+		return space, nil, fmt.Errorf("failed to list the datasets: %w", err)
+	}
+
+	candidates := m.candidates(manifests)
+
+	var evicted []string
+	var errs []error
+
+	for _, cid := range candidates {
+		if space.QuotaUsedBytes <= low {
+			break
+		}
+
+		if err := m.node.Delete(cid); err != nil {
+			errs = append(errs, fmt.Errorf("failed to evict the dataset %s: %w", cid, err))
+			continue
+		}
+
+		evicted = append(evicted, cid)
+
+		space, err = m.node.Space()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the storage usage: %w", err))
+			break
+		}
+	}
+
+	m.mu.Lock()
+	for _, cid := range evicted {
+		delete(m.lastUsed, cid)
+	}
+
+	if err := m.save(); err != nil {
+		errs = append(errs, err)
+	}
+	m.mu.Unlock()
+
+	return space, evicted, errors.Join(errs...)
+}
+
+// candidates returns the unpinned datasets, least recently used first.
+// The datasets seen for the first time are considered used now, so that
+// a dataset is not evicted just after it was stored.
+// The caller saves the pin set.
+func (m *Manager) candidates(manifests []codex.Manifest) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	stored := map[string]bool{}
+	var candidates []string
+
+	for _, manifest := range manifests {
+		stored[manifest.Cid] = true
+
+		if _, ok := m.lastUsed[manifest.Cid]; !ok {
+			m.lastUsed[manifest.Cid] = now
+		}
+
+		if _, ok := m.pins[manifest.Cid]; !ok {
+			candidates = append(candidates, manifest.Cid)
+		}
+	}
+
+	// Forget the datasets deleted from the node
+	for cid := range m.lastUsed {
+		if _, ok := m.pins[cid]; !ok && !stored[cid] {
+			delete(m.lastUsed, cid)
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b string) int {
+		return cmp.Or(m.lastUsed[a].Compare(m.lastUsed[b]), cmp.Compare(a, b))
+	})
+
+	return candidates
+}
+
+// Run reconciles the pin set every options.Interval until the context
+// is done, starting immediately. The errors are passed to options.OnError.
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.options.interval())
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Reconcile(); err != nil && m.options.OnError != nil {
+			m.options.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
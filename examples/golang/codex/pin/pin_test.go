@@ -0,0 +1,235 @@
+package pin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/memory"
+)
+
+// fetchNode counts the Fetch calls and fetches the datasets from a
+// remote node, which the memory node cannot do.
+type fetchNode struct {
+	*memory.Node
+	remote  *memory.Node
+	fetched []string
+}
+
+func (node *fetchNode) Fetch(cid string) (codex.Manifest, error) {
+	node.fetched = append(node.fetched, cid)
+
+	var buf bytes.Buffer
+	if err := node.remote.DownloadStream(cid, codex.DownloadStreamOptions{Writer: &buf}); err != nil {
+		return codex.Manifest{}, err
+	}
+
+	if _, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024}, &buf); err != nil {
+		return codex.Manifest{}, err
+	}
+
+	return node.DownloadManifest(cid)
+}
+
+// upload stores 10 blocks of 1024 bytes, distinct for each seed, so that
+// the datasets do not share blocks, which the node stores once.
+func upload(t *testing.T, node codex.Node, seed string) string {
+	t.Helper()
+
+	var data []byte
+	for i := range 10 {
+		data = append(data, bytes.Repeat([]byte(fmt.Sprintf("%s%d", seed, i)), 1024)[:1024]...)
+	}
+
+	cid, err := node.UploadReader(codex.UploadOptions{ChunkSize: 1024}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	return cid
+}
+
+// clock returns a function advancing by one second at each call.
+func clock() func() time.Time {
+	now := time.Unix(1_000_000, 0)
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestEvictsLeastRecentlyUsedUnpinned(t *testing.T) {
+	node := memory.New()
+	node.QuotaMaxBytes = 35_000
+
+	manager, err := New(node, Options{Path: filepath.Join(t.TempDir(), "pins.json"), HighWaterMark: 0.8, LowWaterMark: 0.65})
+	if err != nil {
+		t.Fatalf("failed to create the manager: %v", err)
+	}
+	manager.now = clock()
+
+	pinned := upload(t, node, "a")
+	older := upload(t, node, "b")
+	newer := upload(t, node, "c")
+
+	if err := manager.Pin(Pin{Cid: pinned, Priority: 1}); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+
+	manager.Touch(older)
+	manager.Touch(newer)
+
+	report, err := manager.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	if !slices.Equal(report.Evicted, []string{older}) {
+		t.Fatalf("expected %s to be evicted, got %v", older, report.Evicted)
+	}
+
+	for cid, expected := range map[string]bool{pinned: true, older: false, newer: true} {
+		if exists, _ := node.Exists(cid); exists != expected {
+			t.Fatalf("expected exists=%v for %s", expected, cid)
+		}
+	}
+
+	// Below the high-water mark, nothing is evicted
+	report, err = manager.Reconcile()
+	if err != nil || len(report.Evicted) > 0 {
+		t.Fatalf("expected no eviction, got %v, err=%v", report.Evicted, err)
+	}
+}
+
+func TestPersistsPinsAndDropsExpired(t *testing.T) {
+	node := memory.New()
+	path := filepath.Join(t.TempDir(), "pins.json")
+
+	kept := upload(t, node, "a")
+	expiring := upload(t, node, "b")
+
+	manager, err := New(node, Options{Path: path})
+	if err != nil {
+		t.Fatalf("failed to create the manager: %v", err)
+	}
+
+	manager.Pin(Pin{Cid: kept, Labels: map[string]string{"app": "backup"}})
+	manager.Pin(Pin{Cid: expiring, Priority: 2, Expiry: time.Now().Add(time.Hour)})
+
+	reloaded, err := New(node, Options{Path: path})
+	if err != nil {
+		t.Fatalf("failed to reload the manager: %v", err)
+	}
+
+	pins := reloaded.Pins()
+	if len(pins) != 2 || pins[0].Cid != expiring || pins[1].Labels["app"] != "backup" {
+		t.Fatalf("unexpected pins after reload: %+v", pins)
+	}
+
+	reloaded.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	report, err := reloaded.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	if !slices.Equal(report.Expired, []string{expiring}) {
+		t.Fatalf("expected %s to expire, got %v", expiring, report.Expired)
+	}
+
+	if _, ok := reloaded.Get(expiring); ok {
+		t.Fatalf("expected the expired pin to be dropped")
+	}
+}
+
+func TestFetchesMissingPinned(t *testing.T) {
+	remote := memory.New()
+	cid := upload(t, remote, "a")
+
+	node := &fetchNode{Node: memory.New(), remote: remote}
+
+	manager, err := New(node, Options{Path: filepath.Join(t.TempDir(), "pins.json")})
+	if err != nil {
+		t.Fatalf("failed to create the manager: %v", err)
+	}
+
+	if err := manager.Pin(Pin{Cid: cid}); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+
+	if err := node.Delete(cid); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	report, err := manager.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	if !slices.Equal(report.Fetched, []string{cid}) || len(node.fetched) != 2 {
+		t.Fatalf("expected the dataset to be fetched on Pin and Reconcile, got %v, calls %v", report.Fetched, node.fetched)
+	}
+
+	if exists, _ := node.Exists(cid); !exists {
+		t.Fatalf("expected the dataset to be stored")
+	}
+}
+
+func TestFetchesPinnedWithStoredManifest(t *testing.T) {
+	remote := memory.New()
+	cid := upload(t, remote, "a")
+
+	node := &fetchNode{Node: memory.New(), remote: remote}
+	upload(t, node, "a")
+
+	manager, err := New(node, Options{Path: filepath.Join(t.TempDir(), "pins.json")})
+	if err != nil {
+		t.Fatalf("failed to create the manager: %v", err)
+	}
+
+	if err := manager.Pin(Pin{Cid: cid}); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+
+	report, err := manager.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	// The manifest is stored, but its blocks may not be
+	if len(report.Fetched) > 0 || !slices.Equal(node.fetched, []string{cid}) {
+		t.Fatalf("expected the dataset to be fetched once without being reported, got %v, calls %v", report.Fetched, node.fetched)
+	}
+}
+
+func TestDoesNotFetchCompletePinnedAgain(t *testing.T) {
+	remote := memory.New()
+	cid := upload(t, remote, "a")
+
+	node := &fetchNode{Node: memory.New(), remote: remote}
+	upload(t, node, "a")
+
+	manager, err := New(node, Options{Path: filepath.Join(t.TempDir(), "pins.json")})
+	if err != nil {
+		t.Fatalf("failed to create the manager: %v", err)
+	}
+
+	if err := manager.Pin(Pin{Cid: cid}); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+
+	for range 3 {
+		if _, err := manager.Reconcile(); err != nil {
+			t.Fatalf("failed to reconcile: %v", err)
+		}
+	}
+
+	// Fetched once by Pin, its blocks may be missing
+	if !slices.Equal(node.fetched, []string{cid}) {
+		t.Fatalf("expected the complete dataset to be fetched once, got %v", node.fetched)
+	}
+}
@@ -0,0 +1,132 @@
+package codex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultReadyPollInterval is the interval between two checks of WaitReady.
+const DefaultReadyPollInterval = 500 * time.Millisecond
+
+// ReadyOptions are the conditions a started node must meet
+// to be considered ready.
+type ReadyOptions struct {
+	// MinPeers is the minimum number of nodes in the DHT routing table.
+	// Content uploaded before the node knows any peer is not discoverable.
+	MinPeers int
+
+	// RequireSpr requires the node to have a signed peer record,
+	// which is needed by the other nodes to bootstrap from it.
+	RequireSpr bool
+
+	// PollInterval is the interval between two checks of WaitReady.
+	// Default is DefaultReadyPollInterval.
+	PollInterval time.Duration
+}
+
+func (o ReadyOptions) pollInterval() time.Duration {
+	if o.PollInterval <= 0 {
+		return DefaultReadyPollInterval
+	}
+
+	return o.PollInterval
+}
+
+// Readiness is the result of a readiness check.
+type Readiness struct {
+	Ready bool   `json:"ready"`
+	Peers int    `json:"peers"`
+	Spr   string `json:"spr,omitempty"`
+
+	// Reasons lists the conditions which are not met
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Error is set when the node cannot be queried
+	Error string `json:"error,omitempty"`
+}
+
+// CheckReady queries the debug info of the node once
+// and checks the readiness conditions.
+func CheckReady(node Node, options ReadyOptions) (Readiness, error) {
+	info, err := node.Debug()
+	if err != nil {
+		return Readiness{Error: err.Error()}, err
+	}
+
+	readiness := Readiness{Peers: len(info.Table.Nodes), Spr: info.Spr}
+
+	if readiness.Peers < options.MinPeers {
+		readiness.Reasons = append(readiness.Reasons, fmt.Sprintf("%d peers in the routing table, %d required", readiness.Peers, options.MinPeers))
+	}
+
+	if options.RequireSpr && info.Spr == "" {
+		readiness.Reasons = append(readiness.Reasons, "no SPR record")
+	}
+
+	readiness.Ready = len(readiness.Reasons) == 0
+	return readiness, nil
+}
+
+// WaitReady polls the debug info of the node until the readiness
+// conditions hold. It returns the last readiness joined to the context
+// error if the context is done before.
+//
+// The node must be started: the errors returned by the debug call
+// are not retried.
+func WaitReady(ctx context.Context, node Node, options ReadyOptions) (Readiness, error) {
+	ticker := time.NewTicker(options.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		readiness, err := CheckReady(node, options)
+		if err != nil {
+			return readiness, err
+		}
+
+		if readiness.Ready {
+			return readiness, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return readiness, fmt.Errorf("node not ready: %v: %w", readiness.Reasons, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// HealthHandler returns an http.Handler for the Kubernetes probes:
+//   - /healthz answers 200 when the node answers the debug call,
+//   - /readyz answers 200 when the readiness conditions hold.
+//
+// Both answer 503 otherwise, with the Readiness as JSON body.
+func HealthHandler(node Node, options ReadyOptions) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		readiness, err := CheckReady(node, options)
+		writeReadiness(w, readiness, err == nil)
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness, err := CheckReady(node, options)
+		writeReadiness(w, readiness, err == nil && readiness.Ready)
+	})
+
+	return mux
+}
+
+func writeReadiness(w http.ResponseWriter, readiness Readiness, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(readiness)
+}
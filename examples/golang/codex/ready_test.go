@@ -0,0 +1,57 @@
+package codex_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/memory"
+)
+
+func TestWaitReadyTimesOut(t *testing.T) {
+	node := memory.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	readiness, err := codex.WaitReady(ctx, node, codex.ReadyOptions{MinPeers: 1, PollInterval: 10 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if readiness.Ready || len(readiness.Reasons) != 1 {
+		t.Fatalf("unexpected readiness %+v", readiness)
+	}
+
+	if _, err := codex.WaitReady(context.Background(), node, codex.ReadyOptions{}); err != nil {
+		t.Fatalf("expected ready without conditions, got %v", err)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	server := httptest.NewServer(codex.HealthHandler(memory.New(), codex.ReadyOptions{RequireSpr: true}))
+	defer server.Close()
+
+	for path, status := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+
+		var readiness codex.Readiness
+		err = json.NewDecoder(resp.Body).Decode(&readiness)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s returned invalid JSON: %v", path, err)
+		}
+
+		if resp.StatusCode != status {
+			t.Fatalf("GET %s: expected status %d, got %d (%+v)", path, status, resp.StatusCode, readiness)
+		}
+	}
+}
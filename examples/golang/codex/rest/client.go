@@ -0,0 +1,376 @@
+// Package rest implements the common node interface on top of the
+// REST API of a remote Codex node.
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+const apiPrefix = "/api/codex/v1"
+
+// Client implements the common node interface.
+var _ codex.Node = (*Client)(nil)
+
+// Client talks to a Codex node through its REST API.
+// The lifecycle of the remote node is not managed by the client:
+// Start only checks that the node is reachable and Stop does nothing.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a client for the node listening on baseURL,
+// for example http://localhost:8080.
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/") + apiPrefix,
+		httpClient: &http.Client{},
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for the requests.
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	c.httpClient = httpClient
+	return c
+}
+
+// debugInfo is the response of the debug/info endpoint.
+type debugInfo struct {
+	codex.DebugInfo
+	Repo  string `json:"repo"`
+	Codex struct {
+		Version  string `json:"version"`
+		Revision string `json:"revision"`
+	} `json:"codex"`
+}
+
+// content is the format of the manifests returned by the API.
+type content struct {
+	Cid      string         `json:"cid"`
+	Manifest codex.Manifest `json:"manifest"`
+}
+
+func (c content) toManifest() codex.Manifest {
+	c.Manifest.Cid = c.Cid
+	return c.Manifest
+}
+
+// do sends the request and returns the response if its
+// status is successful, an error containing the body otherwise.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed the call to %s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+func (c *Client) request(method string, path string, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	return c.do(req)
+}
+
+func (c *Client) getText(path string) (string, error) {
+	resp, err := c.request(http.MethodGet, path, "text/plain")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func (c *Client) getJSON(method string, path string, v any) error {
+	resp, err := c.request(method, path, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *Client) debugInfo() (debugInfo, error) {
+	var info debugInfo
+	err := c.getJSON(http.MethodGet, "/debug/info", &info)
+	return info, err
+}
+
+// Start checks that the remote node is reachable.
+func (c *Client) Start() error {
+	_, err := c.debugInfo()
+	return err
+}
+
+// Stop does nothing, the remote node keeps running.
+func (c *Client) Stop() error {
+	return nil
+}
+
+// Destroy closes the idle connections to the remote node.
+func (c *Client) Destroy() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (c *Client) Version() (string, error) {
+	info, err := c.debugInfo()
+	return info.Codex.Version, err
+}
+
+func (c *Client) Revision() (string, error) {
+	info, err := c.debugInfo()
+	return info.Codex.Revision, err
+}
+
+func (c *Client) Repo() (string, error) {
+	info, err := c.debugInfo()
+	return info.Repo, err
+}
+
+func (c *Client) Spr() (string, error) {
+	return c.getText("/spr")
+}
+
+func (c *Client) PeerId() (string, error) {
+	return c.getText("/peerid")
+}
+
+func (c *Client) Debug() (codex.DebugInfo, error) {
+	info, err := c.debugInfo()
+	return info.DebugInfo, err
+}
+
+func (c *Client) Connect(peerId string, peerAddresses []string) error {
+	query := url.Values{}
+	for _, addr := range peerAddresses {
+		query.Add("addrs", addr)
+	}
+
+	path := "/connect/" + url.PathEscape(peerId)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.request(http.MethodGet, path, "")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// progressReader calls onRead after each read.
+type progressReader struct {
+	r      io.Reader
+	onRead func(n int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.onRead(n)
+	}
+	return n, err
+}
+
+// UploadReader uploads data from an io.Reader. The filename and the mimetype
+// are deduced from options.Filepath. The chunk size is not used by the REST API,
+// the node stores the data with its default block size.
+func (c *Client) UploadReader(options codex.UploadOptions, r io.Reader) (string, error) {
+	options, r, err := codex.SniffMimetype(options, r)
+	if err != nil {
+		return "", err
+	}
+
+	if options.OnProgress != nil {
+		size := codex.ReaderSize(r)
+		total := 0
+		r = &progressReader{r: r, onRead: func(n int) {
+			total += n
+			options.OnProgress(n, total, codex.Percent(total, size), nil)
+		}}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/data", r)
+	if err != nil {
+		return "", err
+	}
+
+	filename := options.Filename
+	if filename == "" && options.Filepath != "" {
+		filename = filepath.Base(options.Filepath)
+	}
+
+	if filename != "" {
+		req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+
+	if options.Mimetype != "" {
+		req.Header.Set("Content-Type", options.Mimetype)
+	} else if mimetype, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(filename))); err == nil {
+		req.Header.Set("Content-Type", mimetype)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	cid, err := io.ReadAll(resp.Body)
+	return string(cid), err
+}
+
+// UploadFile uploads the file located at options.Filepath.
+func (c *Client) UploadFile(options codex.UploadOptions) (string, error) {
+	file, err := os.Open(options.Filepath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return c.UploadReader(options, file)
+}
+
+// DownloadStream downloads the content identified by cid from the local
+// store of the remote node when options.Local is set, from the network otherwise.
+func (c *Client) DownloadStream(cid string, options codex.DownloadStreamOptions) error {
+	if options.Writer == nil && options.Filepath == "" {
+		return errors.New("failed to download: a writer or a filepath is required")
+	}
+
+	path := "/data/" + url.PathEscape(cid)
+	if !options.Local {
+		path += "/network/stream"
+	}
+
+	resp, err := c.request(http.MethodGet, path, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	writers := []io.Writer{}
+	if options.Writer != nil {
+		writers = append(writers, options.Writer)
+	}
+
+	if options.Filepath != "" {
+		file, err := os.Create(options.Filepath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		writers = append(writers, file)
+	}
+
+	size := int64(options.DatasetSize)
+	if options.DatasetSizeAuto {
+		size = resp.ContentLength
+	}
+
+	var body io.Reader = resp.Body
+	if options.OnProgress != nil {
+		total := 0
+		body = &progressReader{r: resp.Body, onRead: func(n int) {
+			total += n
+			options.OnProgress(n, total, codex.Percent(total, size), nil)
+		}}
+	}
+
+	buf := make([]byte, options.ChunkSize.ValOrDefault())
+	_, err = io.CopyBuffer(io.MultiWriter(writers...), body, buf)
+	return err
+}
+
+func (c *Client) DownloadManifest(cid string) (codex.Manifest, error) {
+	var item content
+	err := c.getJSON(http.MethodGet, "/data/"+url.PathEscape(cid)+"/network/manifest", &item)
+	return item.toManifest(), err
+}
+
+func (c *Client) List() ([]codex.Manifest, error) {
+	var list struct {
+		Content []content `json:"content"`
+	}
+
+	if err := c.getJSON(http.MethodGet, "/data", &list); err != nil {
+		return nil, err
+	}
+
+	manifests := make([]codex.Manifest, 0, len(list.Content))
+	for _, item := range list.Content {
+		manifests = append(manifests, item.toManifest())
+	}
+
+	return manifests, nil
+}
+
+func (c *Client) Space() (codex.Space, error) {
+	var space codex.Space
+	err := c.getJSON(http.MethodGet, "/space", &space)
+	return space, err
+}
+
+func (c *Client) Delete(cid string) error {
+	resp, err := c.request(http.MethodDelete, "/data/"+url.PathEscape(cid), "")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (c *Client) Fetch(cid string) (codex.Manifest, error) {
+	var item content
+	err := c.getJSON(http.MethodPost, "/data/"+url.PathEscape(cid)+"/network", &item)
+	return item.toManifest(), err
+}
+
+func (c *Client) Exists(cid string) (bool, error) {
+	var result map[string]bool
+	if err := c.getJSON(http.MethodGet, "/data/"+url.PathEscape(cid)+"/exists", &result); err != nil {
+		return false, err
+	}
+
+	return result[cid], nil
+}
+
+// UpdateLogLevel updates the log level of the remote node.
+func (c *Client) UpdateLogLevel(logLevel string) error {
+	resp, err := c.request(http.MethodPost, "/debug/chronicles/loglevel?level="+url.QueryEscape(logLevel), "")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
@@ -0,0 +1,313 @@
+package codex
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+const DefaultBlockSize = 1024 * 64
+
+type ChunkSize int
+
+// ValOrDefault returns the chunk size or DefaultBlockSize if it is not set.
+func (c ChunkSize) ValOrDefault() int {
+	if c == 0 {
+		return DefaultBlockSize
+	}
+
+	return int(c)
+}
+
+// DefaultProgressBuffer is the number of progress events queued by default.
+const DefaultProgressBuffer = 64
+
+// ProgressOptions configures the delivery of the progress callbacks.
+//
+// The Codex node reports the progress from its own thread. The events are
+// queued and the OnProgress callback is called from a goroutine, the node
+// never waits for it, so a slow callback does not stall the node and the
+// callback can call the node.
+// A panic in the callback is recovered and returned as the error of the
+// upload or download.
+type ProgressOptions struct {
+	// Buffer is the number of events queued before the next ones are
+	// merged into the last one, with the sum of the bytes read. The events
+	// carrying data which must be delivered, like the chunks written to
+	// DownloadStreamOptions.Writer, are never merged: the queue grows
+	// instead. Default is DefaultProgressBuffer.
+	Buffer int
+
+	// Coalesce merges the events received while the callback is running
+	// into a single call, with the sum of the bytes read, whatever the
+	// Buffer. It is ignored when the events carry data
+	// which must be delivered, like the chunks written to
+	// DownloadStreamOptions.Writer.
+	Coalesce bool
+}
+
+// BufferOrDefault returns the buffer size or DefaultProgressBuffer if it is not set.
+func (o ProgressOptions) BufferOrDefault() int {
+	if o.Buffer <= 0 {
+		return DefaultProgressBuffer
+	}
+
+	return o.Buffer
+}
+
+type OnUploadProgressFunc func(read, total int, percent float64, err error)
+
+type UploadOptions struct {
+	// Filepath can be the full path when using UploadFile
+	// otherwise the file name.
+	// It is used to detect the mimetype.
+	Filepath string
+
+	// Filename is the file name stored in the manifest.
+	// Default is the base name of Filepath.
+	Filename string
+
+	// Mimetype is the mimetype stored in the manifest.
+	// Default is the mimetype of the Filename extension. When neither
+	// Filename nor Mimetype is set and Filepath has no extension,
+	// it is detected from the first bytes of the content.
+	Mimetype string
+
+	// ChunkSize is the size of each upload chunk, passed as `blockSize` to the Codex node
+	// store. Default is to 64 KB.
+	ChunkSize ChunkSize
+
+	// OnProgress is a callback function that is called after each chunk is uploaded with:
+	//   - read: the number of bytes read in the last chunk.
+	//   - total: the total number of bytes read so far.
+	//   - percent: the percentage of the total file size that has been uploaded. It is
+	//     determined from a `stat` call if it is a file and from the length of the buffer
+	// 	   if it is a buffer. Otherwise, it is 0, and PercentUnknown for an UploadWriter.
+	//   - err: an error, if one occurred.
+	//
+	// If the chunk size is more than the `chunkSize` parameter, the callback is called
+	// after the block is actually stored in the block store. Otherwise, it is called
+	// after the chunk is sent to the stream.
+	OnProgress OnUploadProgressFunc
+
+	// Progress configures the delivery of OnProgress.
+	Progress ProgressOptions
+
+	// Concurrency is the number of chunks sent by UploadReader without
+	// waiting for the previous ones to be stored. The chunks are stored
+	// in the order they are read. Default is 1, one chunk at a time.
+	// It is ignored by the nodes which cannot pipeline the chunks.
+	Concurrency int
+
+	// TTL sets the expiry of the content to TTL after the upload is
+	// finalized, even if it is sooner than the default block TTL of the node.
+	// Default is 0, the expiry set by the node. It is rounded up to the
+	// second and ignored by the nodes which cannot set the expiry.
+	TTL time.Duration
+
+	// Announce controls the announcement of the content to the network
+	// once it is stored. When set to false, the content is not announced
+	// until Announce is called for its CID. Default is true, although
+	// a node in private mode announces nothing. It is ignored by the
+	// nodes which do not announce the content.
+	Announce *bool
+}
+
+// AnnounceOrDefault returns Announce or true if it is not set.
+func (o UploadOptions) AnnounceOrDefault() bool {
+	if o.Announce == nil {
+		return true
+	}
+
+	return *o.Announce
+}
+
+// TTLSeconds returns the TTL in seconds, rounded up.
+func (o UploadOptions) TTLSeconds() int64 {
+	if o.TTL <= 0 {
+		return 0
+	}
+
+	return int64((o.TTL + time.Second - 1) / time.Second)
+}
+
+type OnDownloadProgressFunc func(read, total int, percent float64, err error)
+
+type DownloadStreamOptions struct {
+	// Writer receives the downloaded data. It is optional if Filepath is set.
+	Writer io.Writer
+
+	// Filepath is the destination file of the download. It is optional
+	// if Writer is set.
+	Filepath string
+
+	// ChunkSize is the size of each chunk read from the node.
+	// Default is to 64 KB.
+	ChunkSize ChunkSize
+
+	// Local retrieves the content from the local store only,
+	// without reaching the network.
+	Local bool
+
+	// DatasetSize is the size of the content, used to compute the
+	// progress percentage. Use DatasetSizeAuto to fetch it from the manifest.
+	DatasetSize int
+
+	// DatasetSizeAuto retrieves the manifest before the download to
+	// get the dataset size.
+	DatasetSizeAuto bool
+
+	// OnProgress is a callback function that is called after each chunk is
+	// downloaded with:
+	//   - read: the number of bytes read in the last chunk.
+	//   - total: the total number of bytes read so far.
+	//   - percent: the percentage of the dataset downloaded, 0 if the dataset
+	//     size is unknown.
+	//   - err: an error, if one occurred.
+	OnProgress OnDownloadProgressFunc
+
+	// Progress configures the delivery of OnProgress and of the writes
+	// to Writer.
+	Progress ProgressOptions
+}
+
+// Manifest describes a dataset stored in Codex.
+type Manifest struct {
+	// Cid is the CID of the manifest itself.
+	Cid string `json:"cid"`
+
+	// TreeCid is the root of the merkle tree built from the blocks.
+	TreeCid string `json:"treeCid"`
+
+	// DatasetSize is the length of the content in bytes.
+	DatasetSize int64 `json:"datasetSize"`
+
+	// BlockSize is the size of each block.
+	BlockSize int `json:"blockSize"`
+
+	Filename string `json:"filename"`
+
+	Mimetype string `json:"mimetype"`
+
+	// Protected indicates if the content is erasure coded.
+	Protected bool `json:"protected"`
+}
+
+// Space describes the storage usage of a node.
+type Space struct {
+	// Number of blocks stored by the node.
+	TotalBlocks int `json:"totalBlocks"`
+
+	// Maximum storage space (in bytes) available for the node.
+	QuotaMaxBytes int64 `json:"quotaMaxBytes"`
+
+	// Amount of storage space (in bytes) currently used for storing files.
+	QuotaUsedBytes int64 `json:"quotaUsedBytes"`
+
+	// Amount of storage reserved (in bytes) for future use.
+	QuotaReservedBytes int64 `json:"quotaReservedBytes"`
+}
+
+// PeerRecord is the signed record of a peer found in the DHT.
+type PeerRecord struct {
+	PeerId string `json:"peerId"`
+
+	// SeqNo is incremented each time the peer updates its record.
+	SeqNo uint64 `json:"seqNo"`
+
+	// Addresses are the multiaddresses of the peer.
+	Addresses []string `json:"addresses"`
+}
+
+// Peer is a peer connected to the node, with the blocks exchanged with it.
+type Peer struct {
+	PeerId string `json:"peerId"`
+
+	// Addresses are the multiaddresses known for the peer.
+	Addresses []string `json:"addresses"`
+
+	// Agent is the agent string of the peer, empty until it is identified.
+	Agent string `json:"agent"`
+
+	// Direction is "outbound" when the node has dialed the peer,
+	// "inbound" otherwise.
+	Direction string `json:"direction"`
+
+	BlocksSent     int64 `json:"blocksSent"`
+	BytesSent      int64 `json:"bytesSent"`
+	BlocksReceived int64 `json:"blocksReceived"`
+	BytesReceived  int64 `json:"bytesReceived"`
+
+	// PendingWants is the number of blocks wanted by the peer
+	// and not sent yet.
+	PendingWants int `json:"pendingWants"`
+
+	// PendingRequests is the number of blocks requested to the peer
+	// and not received yet.
+	PendingRequests int `json:"pendingRequests"`
+}
+
+// DhtNode is an entry of the DHT routing table.
+type DhtNode struct {
+	NodeId  string `json:"nodeId"`
+	PeerId  string `json:"peerId"`
+	Record  string `json:"record"`
+	Address string `json:"address"`
+	Seen    bool   `json:"seen"`
+}
+
+type RoutingTable struct {
+	LocalNode DhtNode   `json:"localNode"`
+	Nodes     []DhtNode `json:"nodes"`
+}
+
+// DebugInfo contains information about the node and its DHT routing table.
+type DebugInfo struct {
+	Id                string       `json:"id"`
+	Addrs             []string     `json:"addrs"`
+	Spr               string       `json:"spr"`
+	AnnounceAddresses []string     `json:"announceAddresses"`
+	Table             RoutingTable `json:"table"`
+}
+
+// ReaderSize returns the size of the data behind r if it can be known
+// without consuming it, 0 otherwise.
+func ReaderSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case *os.File:
+		stat, err := v.Stat()
+		if err != nil {
+			return 0
+		}
+		return stat.Size()
+	case *bytes.Buffer:
+		return int64(v.Len())
+	case *bytes.Reader:
+		return int64(v.Len())
+	case interface{ Len() int }:
+		return int64(v.Len())
+	default:
+		return 0
+	}
+}
+
+// PercentUnknown is the percent given to the progress callback of an
+// UploadWriter, which cannot know the size of the content.
+const PercentUnknown = -1
+
+// Percent returns the percentage of total compared to size, capped to 100
+// because the last block could be a bit over the size due to padding
+// on the chunk size.
+func Percent(total int, size int64) float64 {
+	if size <= 0 {
+		return 0
+	}
+
+	percent := float64(total) / float64(size) * 100.0
+	if percent > 100.0 {
+		percent = 100.0
+	}
+
+	return percent
+}
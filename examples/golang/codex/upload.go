@@ -0,0 +1,201 @@
+package codex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// UploadResult describes a content uploaded by UploadReaderResult
+// or UploadFileResult.
+type UploadResult struct {
+	Cid string
+
+	// Bytes is the size of the content.
+	Bytes int64
+
+	// Blocks is the number of blocks of the dataset.
+	Blocks int
+
+	// Duration is the time taken by the upload.
+	Duration time.Duration
+
+	// Sha256 is the hex encoded SHA-256 of the content.
+	Sha256 string
+
+	// Filename and Mimetype are the metadata stored in the manifest.
+	Filename string
+	Mimetype string
+}
+
+// hashingReader hashes and counts the bytes read from r.
+type hashingReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+	read int64
+}
+
+func newHashingReader(r io.Reader) *hashingReader {
+	return &hashingReader{r: r, hash: sha256.New(), size: ReaderSize(r)}
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	h.read += int64(n)
+
+	return n, err
+}
+
+// Len returns the number of bytes not read yet, if the size of r is known,
+// so that ReaderSize works on the wrapped reader.
+func (h *hashingReader) Len() int {
+	return int(max(h.size-h.read, 0))
+}
+
+// UploadReaderResult uploads the data of r like node.UploadReader and
+// describes the uploaded content. The SHA-256 is computed while the data
+// is read for the upload, the metadata come from the manifest.
+func UploadReaderResult(node Node, options UploadOptions, r io.Reader) (UploadResult, error) {
+	start := time.Now()
+	hr := newHashingReader(r)
+
+	cid, err := node.UploadReader(options, hr)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	duration := time.Since(start)
+
+	manifest, err := node.DownloadManifest(cid)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to get the manifest of %s: %w", cid, err)
+	}
+
+	manifest.Cid = cid
+	result := NewUploadResult(manifest, hex.EncodeToString(hr.hash.Sum(nil)), duration)
+	result.Bytes = hr.read
+
+	return result, nil
+}
+
+// NewUploadResult describes the content of the manifest, uploaded in
+// duration, with the hex encoded SHA-256 of the content.
+func NewUploadResult(manifest Manifest, sha256 string, duration time.Duration) UploadResult {
+	result := UploadResult{
+		Cid:      manifest.Cid,
+		Bytes:    manifest.DatasetSize,
+		Duration: duration,
+		Sha256:   sha256,
+		Filename: manifest.Filename,
+		Mimetype: manifest.Mimetype,
+	}
+
+	if manifest.BlockSize > 0 {
+		result.Blocks = int((manifest.DatasetSize + int64(manifest.BlockSize) - 1) / int64(manifest.BlockSize))
+	}
+
+	return result
+}
+
+// UploadFileResult uploads the file located at options.Filepath and
+// describes the uploaded content, see UploadReaderResult.
+// Unlike node.UploadFile, the file is read in Go to compute its
+// SHA-256 while it is uploaded.
+func UploadFileResult(node Node, options UploadOptions) (UploadResult, error) {
+	f, err := os.Open(options.Filepath)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer f.Close()
+
+	return UploadReaderResult(node, options, f)
+}
+
+// sniffLen is the number of bytes considered by http.DetectContentType.
+const sniffLen = 512
+
+// NeedsSniffing reports whether the mimetype of the content must be
+// detected from its first bytes: neither Filename nor Mimetype is set
+// and the extension of Filepath cannot give the mimetype.
+func (o UploadOptions) NeedsSniffing() bool {
+	return o.Filename == "" && o.Mimetype == "" && filepath.Ext(o.Filepath) == ""
+}
+
+// SniffMimetype sets options.Mimetype from the first bytes of r, with
+// http.DetectContentType, if options.NeedsSniffing. It returns the
+// options and a reader returning the whole content, r included.
+// The size of r is kept for ReaderSize.
+func SniffMimetype(options UploadOptions, r io.Reader) (UploadOptions, io.Reader, error) {
+	if !options.NeedsSniffing() {
+		return options, r, nil
+	}
+
+	size := ReaderSize(r)
+	head := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return options, nil, err
+	}
+
+	head = head[:n]
+
+	// Without the parameters, like the mimetypes deduced from the extensions
+	options.Mimetype, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+
+	return options, &prefixedReader{head: head, r: r, size: size}, nil
+}
+
+// SniffFileMimetype is SniffMimetype for the file located at
+// options.Filepath, when the file is read by the node.
+func SniffFileMimetype(options UploadOptions) (UploadOptions, error) {
+	if !options.NeedsSniffing() {
+		return options, nil
+	}
+
+	f, err := os.Open(options.Filepath)
+	if err != nil {
+		return options, err
+	}
+	defer f.Close()
+
+	options, _, err = SniffMimetype(options, f)
+	return options, err
+}
+
+// prefixedReader returns head, then the data of r.
+type prefixedReader struct {
+	head []byte
+	r    io.Reader
+	size int64
+	read int64
+}
+
+func (p *prefixedReader) Read(b []byte) (int, error) {
+	var n int
+	var err error
+
+	if len(p.head) > 0 {
+		n = copy(b, p.head)
+		p.head = p.head[n:]
+	} else {
+		n, err = p.r.Read(b)
+	}
+
+	p.read += int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes not read yet, if the size of r is known.
+func (p *prefixedReader) Len() int {
+	return int(max(p.size-p.read, 0))
+}
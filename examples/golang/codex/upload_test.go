@@ -0,0 +1,93 @@
+package codex_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/memory"
+)
+
+func TestUploadReaderResult(t *testing.T) {
+	node := memory.New()
+	data := bytes.Repeat([]byte("codex"), 1000)
+	digest := sha256.Sum256(data)
+
+	result, err := codex.UploadReaderResult(node, codex.UploadOptions{Filepath: "data.txt", ChunkSize: 1024}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	expected := codex.UploadResult{
+		Cid:      result.Cid,
+		Bytes:    int64(len(data)),
+		Blocks:   5,
+		Duration: result.Duration,
+		Sha256:   hex.EncodeToString(digest[:]),
+		Filename: "data.txt",
+		Mimetype: "text/plain",
+	}
+
+	if result != expected {
+		t.Fatalf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestUploadFileResultReportsProgress(t *testing.T) {
+	node := memory.New()
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("codex"), 1000), 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	var percent float64
+	options := codex.UploadOptions{
+		Filepath:  path,
+		ChunkSize: 1024,
+		OnProgress: func(read, total int, p float64, err error) {
+			percent = p
+		},
+	}
+
+	result, err := codex.UploadFileResult(node, options)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if result.Bytes != 5000 || result.Filename != "data.bin" || percent != 100 {
+		t.Fatalf("unexpected result %+v, progress %v%%", result, percent)
+	}
+}
+
+func TestUploadMetadata(t *testing.T) {
+	html := []byte("<!DOCTYPE html><html><body>codex</body></html>")
+
+	tests := []struct {
+		name     string
+		options  codex.UploadOptions
+		filename string
+		mimetype string
+	}{
+		{"extension", codex.UploadOptions{Filepath: "page.txt"}, "page.txt", "text/plain"},
+		{"sniffed", codex.UploadOptions{Filepath: "page"}, "page", "text/html"},
+		{"filename", codex.UploadOptions{Filepath: "page", Filename: "report.pdf"}, "report.pdf", "application/pdf"},
+		{"mimetype", codex.UploadOptions{Filepath: "page.txt", Mimetype: "application/x-codex"}, "page.txt", "application/x-codex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := codex.UploadReaderResult(memory.New(), tt.options, bytes.NewReader(html))
+			if err != nil {
+				t.Fatalf("upload failed: %v", err)
+			}
+
+			if result.Filename != tt.filename || result.Mimetype != tt.mimetype || result.Bytes != int64(len(html)) {
+				t.Fatalf("expected %s %s, got %+v", tt.filename, tt.mimetype, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+)
+
+var uploadOptions struct {
+	chunkSize   int
+	concurrency int
+	filename    string
+	mimetype    string
+	progress    bool
+}
+
+func uploadFlags(fs *flag.FlagSet) {
+	fs.IntVar(&uploadOptions.chunkSize, "chunk-size", 0, "size of the upload chunks (default: 64 KiB)")
+	fs.IntVar(&uploadOptions.concurrency, "concurrency", 1, "number of chunks in flight when uploading stdin")
+	fs.StringVar(&uploadOptions.filename, "filename", "", "filename stored in the manifest when uploading stdin")
+	fs.StringVar(&uploadOptions.mimetype, "mimetype", "", "mimetype stored in the manifest (default: from the extension or the content)")
+	fs.BoolVar(&uploadOptions.progress, "progress", true, "show a progress bar on stderr")
+}
+
+type uploadResult struct {
+	File string `json:"file"`
+	Cid  string `json:"cid"`
+}
+
+func runUpload(cmd *cmdContext, args []string) error {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	results := []uploadResult{}
+
+	for _, file := range args {
+		options := codex.UploadOptions{
+			ChunkSize:   codex.ChunkSize(uploadOptions.chunkSize),
+			Concurrency: uploadOptions.concurrency,
+			Mimetype:    uploadOptions.mimetype,
+		}
+
+		var bar *progressBar
+		if uploadOptions.progress {
+			bar = newProgressBar(os.Stderr, filepath.Base(file))
+			options.OnProgress = func(_, total int, percent float64, _ error) {
+				bar.update(int64(total), percent)
+			}
+		}
+
+		var cid string
+		var err error
+		if file == "-" {
+			options.Filepath = uploadOptions.filename
+			cid, err = cmd.node.UploadReader(options, os.Stdin)
+		} else {
+			options.Filepath, err = filepath.Abs(file)
+			if err == nil {
+				cid, err = cmd.node.UploadFile(options)
+			}
+		}
+
+		bar.done()
+
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+
+		results = append(results, uploadResult{File: file, Cid: cid})
+	}
+
+	return cmd.print(results, func() {
+		for _, r := range results {
+			fmt.Printf("%s\t%s\n", r.Cid, r.File)
+		}
+	})
+}
+
+var downloadOptions struct {
+	output   string
+	local    bool
+	progress bool
+}
+
+func downloadFlags(fs *flag.FlagSet) {
+	fs.StringVar(&downloadOptions.output, "o", "", "output file (default: stdout)")
+	fs.BoolVar(&downloadOptions.local, "local", false, "download from the local store only")
+	fs.BoolVar(&downloadOptions.progress, "progress", true, "show a progress bar on stderr when -o is set")
+}
+
+type downloadResult struct {
+	Cid    string `json:"cid"`
+	Output string `json:"output"`
+	Bytes  int64  `json:"bytes"`
+}
+
+func runDownload(cmd *cmdContext, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected exactly one cid")
+	}
+
+	cid := args[0]
+
+	if downloadOptions.output == "" && cmd.json {
+		return errors.New("--json requires -o, the content is written to stdout")
+	}
+
+	var w io.Writer = os.Stdout
+	if downloadOptions.output != "" {
+		file, err := os.Create(downloadOptions.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	var total int64
+	var bar *progressBar
+	if downloadOptions.progress && downloadOptions.output != "" {
+		bar = newProgressBar(os.Stderr, cid)
+	}
+
+	err := cmd.node.DownloadStream(cid, codex.DownloadStreamOptions{
+		Writer:          w,
+		Local:           downloadOptions.local,
+		DatasetSizeAuto: bar != nil,
+		OnProgress: func(_, read int, percent float64, _ error) {
+			total = int64(read)
+			bar.update(total, percent)
+		},
+	})
+
+	bar.done()
+
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", cid, err)
+	}
+
+	if downloadOptions.output == "" {
+		return nil
+	}
+
+	result := downloadResult{Cid: cid, Output: downloadOptions.output, Bytes: total}
+	return cmd.print(result, func() {
+		fmt.Printf("Downloaded %s to %s (%s)\n", cid, result.Output, formatBytes(total))
+	})
+}
+
+func runList(cmd *cmdContext, args []string) error {
+	manifests, err := cmd.node.List()
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(manifests, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CID\tFILENAME\tSIZE\tMIMETYPE")
+		for _, m := range manifests {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Cid, m.Filename, formatBytes(m.DatasetSize), m.Mimetype)
+		}
+		w.Flush()
+	})
+}
+
+func runDelete(cmd *cmdContext, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected at least one cid")
+	}
+
+	deleted := []string{}
+	for _, cid := range args {
+		if err := cmd.node.Delete(cid); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", cid, err)
+		}
+		deleted = append(deleted, cid)
+	}
+
+	return cmd.print(map[string][]string{"deleted": deleted}, func() {
+		for _, cid := range deleted {
+			fmt.Printf("Deleted %s\n", cid)
+		}
+	})
+}
+
+func printManifest(m codex.Manifest) {
+	fmt.Printf("CID:         %s\n", m.Cid)
+	fmt.Printf("Tree CID:    %s\n", m.TreeCid)
+	fmt.Printf("Filename:    %s\n", m.Filename)
+	fmt.Printf("Mimetype:    %s\n", m.Mimetype)
+	fmt.Printf("Size:        %s\n", formatBytes(m.DatasetSize))
+	fmt.Printf("Block size:  %s\n", formatBytes(int64(m.BlockSize)))
+	fmt.Printf("Protected:   %v\n", m.Protected)
+}
+
+// runFetch starts the download of the dataset in the background.
+// With an embedded node, the download stops when the command exits,
+// so it is mostly useful with --api.
+func runFetch(cmd *cmdContext, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected exactly one cid")
+	}
+
+	manifest, err := cmd.node.Fetch(args[0])
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(manifest, func() {
+		printManifest(manifest)
+	})
+}
+
+func runExists(cmd *cmdContext, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected exactly one cid")
+	}
+
+	exists, err := cmd.node.Exists(args[0])
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(map[string]bool{args[0]: exists}, func() {
+		fmt.Println(exists)
+	})
+}
+
+func runSpace(cmd *cmdContext, args []string) error {
+	space, err := cmd.node.Space()
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(space, func() {
+		fmt.Printf("Total blocks:    %d\n", space.TotalBlocks)
+		fmt.Printf("Quota max:       %s\n", formatBytes(space.QuotaMaxBytes))
+		fmt.Printf("Quota used:      %s\n", formatBytes(space.QuotaUsedBytes))
+		fmt.Printf("Quota reserved:  %s\n", formatBytes(space.QuotaReservedBytes))
+	})
+}
+
+type info struct {
+	PeerId   string          `json:"peerId"`
+	Spr      string          `json:"spr"`
+	Version  string          `json:"version"`
+	Revision string          `json:"revision"`
+	Debug    codex.DebugInfo `json:"debug"`
+}
+
+func runInfo(cmd *cmdContext, args []string) error {
+	var i info
+	var err error
+
+	if i.PeerId, err = cmd.node.PeerId(); err != nil {
+		return err
+	}
+
+	// The SPR is not available when the node has no announce address
+	i.Spr, _ = cmd.node.Spr()
+
+	if i.Version, err = cmd.node.Version(); err != nil {
+		return err
+	}
+
+	if i.Revision, err = cmd.node.Revision(); err != nil {
+		return err
+	}
+
+	if i.Debug, err = cmd.node.Debug(); err != nil {
+		return err
+	}
+
+	return cmd.print(i, func() {
+		fmt.Printf("Peer ID:   %s\n", i.PeerId)
+		fmt.Printf("SPR:       %s\n", i.Spr)
+		fmt.Printf("Version:   %s\n", i.Version)
+		fmt.Printf("Revision:  %s\n", i.Revision)
+		fmt.Printf("Addresses: %v\n", i.Debug.Addrs)
+		fmt.Printf("Announce:  %v\n", i.Debug.AnnounceAddresses)
+
+		fmt.Printf("\nDHT table (%d nodes):\n", len(i.Debug.Table.Nodes))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PEER ID\tADDRESS\tSEEN")
+		for _, n := range i.Debug.Table.Nodes {
+			fmt.Fprintf(w, "%s\t%s\t%v\n", n.PeerId, n.Address, n.Seen)
+		}
+		w.Flush()
+	})
+}
+
+func runConnect(cmd *cmdContext, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected a peer id")
+	}
+
+	if err := cmd.node.Connect(args[0], args[1:]); err != nil {
+		return err
+	}
+
+	return cmd.print(map[string]string{"connected": args[0]}, func() {
+		fmt.Printf("Connected to %s\n", args[0])
+	})
+}
@@ -0,0 +1,3 @@
+module github.com/codex-storage/nim-codex/examples/golang
+
+go 1.23
@@ -0,0 +1,276 @@
+// codex-go is a command line tool built on top of the Go bindings.
+//
+// By default, each command runs a Codex node embedded in the process,
+// using the data dir given with --data-dir. When --api is set, the
+// command is sent to a running node through its REST API instead.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/codex-storage/nim-codex/examples/golang/codex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/libcodex"
+	"github.com/codex-storage/nim-codex/examples/golang/codex/rest"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(cmd *cmdContext, args []string) error
+
+	// flags registers the flags specific to the command
+	flags func(fs *flag.FlagSet)
+}
+
+var commands = map[string]command{
+	"upload": {
+		usage:       "upload [flags] [file...]",
+		description: "Upload files, or stdin when no file is given or the file is -",
+		flags:       uploadFlags,
+		run:         runUpload,
+	},
+	"download": {
+		usage:       "download [flags] <cid>",
+		description: "Download content to a file or to stdout",
+		flags:       downloadFlags,
+		run:         runDownload,
+	},
+	"ls": {
+		usage:       "ls [flags]",
+		description: "List the datasets stored by the node",
+		run:         runList,
+	},
+	"rm": {
+		usage:       "rm [flags] <cid...>",
+		description: "Delete datasets or blocks from the node",
+		run:         runDelete,
+	},
+	"fetch": {
+		usage:       "fetch [flags] <cid>",
+		description: "Start downloading a dataset from the network to the node",
+		run:         runFetch,
+	},
+	"exists": {
+		usage:       "exists [flags] <cid>",
+		description: "Check if a cid is stored by the node",
+		run:         runExists,
+	},
+	"space": {
+		usage:       "space [flags]",
+		description: "Show the storage usage of the node",
+		run:         runSpace,
+	},
+	"info": {
+		usage:       "info [flags]",
+		description: "Show the peer id, SPR, version, revision and DHT table of the node",
+		run:         runInfo,
+	},
+	"connect": {
+		usage:       "connect [flags] <peer id> [address...]",
+		description: "Connect to a peer, looked up in the DHT when no address is given",
+		run:         runConnect,
+	},
+}
+
+// stringsFlag is a flag which can be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// cmdContext contains the flags shared by all the commands
+// and the node used to run the command.
+type cmdContext struct {
+	json bool
+
+	api            string
+	dataDir        string
+	logLevel       string
+	discoveryPort  int
+	listenAddrs    stringsFlag
+	bootstrapNodes stringsFlag
+
+	shutdownTimeout time.Duration
+
+	node codex.Node
+}
+
+// shutdowner is implemented by the nodes which can drain
+// their in-flight transfers before stopping.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+func (cmd *cmdContext) register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "print the result as JSON")
+	fs.StringVar(&cmd.api, "api", "", "URL of a running Codex node REST API, e.g. http://localhost:8080 (default: embedded node)")
+	fs.StringVar(&cmd.dataDir, "data-dir", "", "data dir of the embedded node (default: Codex default data dir)")
+	fs.StringVar(&cmd.logLevel, "log-level", "ERROR", "log level of the embedded node")
+	fs.IntVar(&cmd.discoveryPort, "disc-port", 0, "discovery (UDP) port of the embedded node (default: 8090)")
+	fs.Var(&cmd.listenAddrs, "listen-addrs", "multi address to listen on, can be repeated")
+	fs.Var(&cmd.bootstrapNodes, "bootstrap-node", "SPR of a bootstrap node, can be repeated")
+	fs.DurationVar(&cmd.shutdownTimeout, "shutdown-timeout", 30*time.Second, "time given to the running transfers to complete on SIGINT or SIGTERM")
+}
+
+// open creates and starts the node used by the command.
+func (cmd *cmdContext) open() error {
+	if cmd.api != "" {
+		cmd.node = rest.New(cmd.api)
+		return cmd.node.Start()
+	}
+
+	node, err := libcodex.New(libcodex.Config{
+		LogLevel:       cmd.logLevel,
+		DataDir:        cmd.dataDir,
+		DiscoveryPort:  cmd.discoveryPort,
+		ListenAddrs:    cmd.listenAddrs,
+		BootstrapNodes: cmd.bootstrapNodes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Codex node: %w", err)
+	}
+
+	if err := node.Start(); err != nil {
+		node.Close()
+		return fmt.Errorf("failed to start Codex node: %w", err)
+	}
+
+	cmd.node = node
+	return nil
+}
+
+// close stops and destroys the node.
+func (cmd *cmdContext) close() error {
+	if cmd.node == nil {
+		return nil
+	}
+
+	if closer, ok := cmd.node.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return errors.Join(cmd.node.Stop(), cmd.node.Destroy())
+}
+
+// shutdown waits for the running transfers up to --shutdown-timeout,
+// cancels the remaining ones, then stops and destroys the node.
+func (cmd *cmdContext) shutdown() error {
+	node, ok := cmd.node.(shutdowner)
+	if !ok {
+		return cmd.close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.shutdownTimeout)
+	defer cancel()
+
+	return node.Shutdown(ctx)
+}
+
+// print prints v as JSON when --json is set, using text otherwise.
+func (cmd *cmdContext) print(v any, text func()) error {
+	if !cmd.json {
+		text()
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: codex-go <command> [flags] [args]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].description)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun 'codex-go <command> -h' for the flags of a command.\n")
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing command")
+	}
+
+	c, ok := commands[args[0]]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	cmd := &cmdContext{}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: codex-go %s\n\n%s\n\nFlags:\n", c.usage, c.description)
+		fs.PrintDefaults()
+	}
+
+	cmd.register(fs)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := cmd.open(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.run(cmd, fs.Args())
+	}()
+
+	select {
+	case err := <-done:
+		return errors.Join(err, cmd.close())
+	case <-ctx.Done():
+		// A second signal kills the process
+		stop()
+
+		fmt.Fprintf(os.Stderr, "Shutting down, waiting up to %s for the running transfers\n", cmd.shutdownTimeout)
+
+		// The command is not waited for: the transfers which did not
+		// complete are cancelled and the process exits anyway
+		return errors.Join(errors.New("interrupted"), cmd.shutdown())
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
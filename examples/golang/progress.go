@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders the progress of a transfer on a single line.
+// A nil progressBar does nothing, so it can be used when the
+// progress is disabled.
+type progressBar struct {
+	out   io.Writer
+	label string
+	last  string
+}
+
+func newProgressBar(out io.Writer, label string) *progressBar {
+	return &progressBar{out: out, label: label}
+}
+
+// update renders the bar. When the percent is unknown (0), only the
+// number of bytes transferred is displayed.
+func (p *progressBar) update(total int64, percent float64) {
+	if p == nil {
+		return
+	}
+
+	var line string
+	if percent > 0 {
+		filled := int(percent / 100 * progressBarWidth)
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+		line = fmt.Sprintf("%s [%s] %5.1f%% %s", p.label, bar, percent, formatBytes(total))
+	} else {
+		line = fmt.Sprintf("%s %s", p.label, formatBytes(total))
+	}
+
+	// Avoid flooding the terminal when the line does not change
+	if line == p.last {
+		return
+	}
+
+	p.last = line
+	fmt.Fprintf(p.out, "\r%s", line)
+}
+
+// done terminates the line of the bar.
+func (p *progressBar) done() {
+	if p == nil || p.last == "" {
+		return
+	}
+
+	fmt.Fprintln(p.out)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,111 @@
+{.push raises: [].}
+
+## Announcement of the datasets stored in the node to the network.
+##
+## The datasets uploaded with the announcement disabled are private:
+## the advertiser does not announce their manifest until they are
+## published with `publish`. In private mode, the advertiser only
+## announces the published datasets.
+##
+## The policy is written to a file of the data dir, so the private
+## datasets are not announced by the advertiser loop after a restart.
+
+import std/[os, json, options, sets]
+import chronicles
+import results
+import libp2p/cid
+import ../codex/conf
+import ../codex/blockexchange
+
+from ../codex/codex import CodexServer, node, config
+from ../codex/node import engine
+
+logScope:
+  topics = "codexlib codexlibannounce"
+
+const AnnouncePolicyFile* = "announce.json"
+
+type AnnouncePolicy = object
+  path: string
+  privateMode: bool
+  # Uploaded with the announcement disabled and not published since
+  private: HashSet[Cid]
+  # Published with `publish`, announced even in private mode
+  published: HashSet[Cid]
+
+var announcePolicy {.threadvar.}: AnnouncePolicy
+
+proc toJson(cids: HashSet[Cid]): JsonNode =
+  result = newJArray()
+  for cid in cids:
+    result.add(%($cid))
+
+proc toCids(node: JsonNode): HashSet[Cid] =
+  for item in node.getElems():
+    let cid = Cid.init(item.getStr()).valueOr:
+      warn "Invalid cid in the announce policy", cid = item.getStr()
+      continue
+    result.incl(cid)
+
+proc save(): Result[void, string] =
+  let content = $(
+    %*{
+      "private": announcePolicy.private.toJson(),
+      "published": announcePolicy.published.toJson(),
+    }
+  )
+
+  try:
+    writeFile(announcePolicy.path, content)
+  except IOError as e:
+    return err("Failed to write the announce policy: " & e.msg)
+
+  return ok()
+
+proc isAnnounced*(cid: Cid): bool =
+  ## Returns true when the advertiser announces the manifest of the cid.
+
+  if cid in announcePolicy.published:
+    return true
+
+  return not announcePolicy.privateMode and cid notin announcePolicy.private
+
+proc initAnnouncePolicy*(server: CodexServer): Result[void, string] =
+  ## Loads the policy from the data dir of the node and installs it
+  ## on its advertiser.
+
+  announcePolicy = AnnouncePolicy(
+    path: string(server.config.dataDir) / AnnouncePolicyFile,
+    privateMode: server.config.privateMode,
+  )
+
+  if fileExists(announcePolicy.path):
+    try:
+      let content = parseJson(readFile(announcePolicy.path))
+      announcePolicy.private = content{"private"}.toCids()
+      announcePolicy.published = content{"published"}.toCids()
+    except CatchableError as e:
+      return err("Failed to read the announce policy: " & e.msg)
+
+  server.node.engine.advertiser.filter = some AdvertiseFilter(isAnnounced)
+
+  return ok()
+
+proc markPrivate*(cid: Cid) =
+  ## Keeps the advertiser from announcing the dataset, until it is
+  ## published.
+
+  announcePolicy.private.incl(cid)
+  announcePolicy.published.excl(cid)
+
+  let res = save()
+  if res.isErr:
+    error "Failed to mark the dataset as private", cid, error = res.error
+
+proc publish*(cid: Cid): Result[void, string] =
+  ## Lets the advertiser announce the dataset, in private mode too.
+
+  announcePolicy.private.excl(cid)
+  announcePolicy.published.incl(cid)
+
+  return save()
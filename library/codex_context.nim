@@ -14,6 +14,7 @@ import chronos
 import chronos/threadsync
 import taskpools/channels_spsc_single
 import ./ffi_types
+import ./events/event_emitter
 import ./codex_thread_requests/[codex_thread_request]
 
 from ../codex/codex import CodexServer
@@ -76,6 +77,10 @@ template callEventCallback(ctx: ptr CodexContext, eventName: string, body: untyp
         RET_ERR, unsafeAddr msg[0], cast[csize_t](len(msg)), ctx[].eventUserData
       )
 
+proc onEvent(ctx: ptr CodexContext, json: string) =
+  callEventCallback(ctx, "onEvent"):
+    json
+
 proc sendRequestToCodexThread*(
     ctx: ptr CodexContext,
     reqType: RequestType,
@@ -125,6 +130,12 @@ proc sendRequestToCodexThread*(
 proc runCodex(ctx: ptr CodexContext) {.async: (raises: []).} =
   var codex: CodexServer
 
+  # The events emitted by the requests are sent to the client of this context
+  setEventEmitter(
+    proc(event: string) {.gcsafe, raises: [].} =
+      ctx.onEvent(event)
+  )
+
   while true:
     try:
       # Wait until a request is available
@@ -3,7 +3,7 @@
 ## START_NODE: start the provided Codex node.
 ## STOP_NODE: stop the provided Codex node.
 
-import std/[options, json, strutils, net, os]
+import std/[atomics, options, json, strutils, net, os]
 import codexdht/discv5/spr
 import stew/shims/parseutils
 import contractabi/address
@@ -17,16 +17,24 @@ import libp2p
 import json_serialization
 import json_serialization/std/[options, net]
 import ../../alloc
+import ../../announce_policy
+import ../../events/[event_emitter, json_peer_event]
 import ../../../codex/conf
 import ../../../codex/utils
 import ../../../codex/utils/[keyutils, fileutils]
 import ../../../codex/units
+import ../../../codex/peergater
 
-from ../../../codex/codex import CodexServer, new, start, stop, close
+from ../../../codex/codex import CodexServer, new, start, stop, close, peerGater
 
 logScope:
   topics = "codexlib codexliblifecycle"
 
+# The metrics registry is global to the process, several nodes cannot
+# start their own metrics server. The server is started by the first
+# node enabling the metrics and it exposes the metrics of all the nodes.
+var metricsStarted: Atomic[bool]
+
 type NodeLifecycleMsgType* = enum
   CREATE_NODE
   START_NODE
@@ -55,6 +63,12 @@ proc readValue*(r: var JsonReader, val: var SignedPeerRecord) =
       newException(SerializationError, "Cannot parse the signed peer: " & res.error())
   val = res.get()
 
+proc readValue*(r: var JsonReader, val: var PeerId) =
+  let res = PeerId.parse(r.readValue(string))
+  if res.isErr:
+    raise newException(SerializationError, "Cannot parse the peer ID: " & res.error())
+  val = res.get()
+
 proc readValue*(r: var JsonReader, val: var ThreadCount) =
   val = ThreadCount(r.readValue(int))
 
@@ -116,7 +130,8 @@ proc createCodex(
   except ValueError as err:
     return err("Failed to create codex: invalid value for log level: " & err.msg)
 
-  conf.setupMetrics()
+  if conf.metricsEnabled and not metricsStarted.exchange(true):
+    conf.setupMetrics()
 
   if not (checkAndCreateDataDir((conf.dataDir).string)):
     # We are unable to access/create data folder or data folder's
@@ -150,6 +165,12 @@ proc createCodex(
     except Exception as exc:
       return err("Failed to create codex: " & exc.msg)
 
+  server.initAnnouncePolicy().isOkOr:
+    return err("Failed to create codex: " & error)
+
+  server.peerGater.onRejected = proc(peerId: PeerId, incoming: bool) =
+    emitEvent(JsonPeerRejectedEvent.new($peerId, incoming))
+
   return ok(server)
 
 proc process*(
@@ -2,41 +2,65 @@
 
 ## This file contains the P2p request type that will be handled.
 ## CONNECT: connect to a peer with the provided peer ID and optional addresses.
+## FIND_PROVIDERS: find the peers providing a cid in the DHT.
+## ANNOUNCE: announce in the DHT that the node provides a cid.
+## ALLOW_PEER: accept the connections of a peer.
+## DENY_PEER: reject the connections of a peer and disconnect it.
+## DISCONNECT: close the connections of a peer.
+## PEERS: list the connected peers with their block exchange statistics.
 
-import std/[options]
+import std/[options, sequtils, sets]
 import chronos
 import chronicles
+import questionable
+import questionable/results
 import libp2p
 import ../../alloc
+import ../../announce_policy
 import ../../../codex/node
+import ../../../codex/discovery
+import ../../../codex/manifest
+import ../../../codex/rest/json
+import ../../../codex/peergater
+import ../../../codex/blockexchange
 
-from ../../../codex/codex import CodexServer, node
+from ../../../codex/codex import CodexServer, node, peerGater
 
 logScope:
   topics = "codexlib codexlibp2p"
 
 type NodeP2PMsgType* = enum
   CONNECT
+  FIND_PROVIDERS
+  ANNOUNCE
+  ALLOW_PEER
+  DENY_PEER
+  DISCONNECT
+  PEERS
 
 type NodeP2PRequest* = object
   operation: NodeP2PMsgType
   peerId: cstring
   peerAddresses: seq[cstring]
+  cid: cstring
 
 proc createShared*(
     T: type NodeP2PRequest,
     op: NodeP2PMsgType,
     peerId: cstring = "",
     peerAddresses: seq[cstring] = @[],
+    cid: cstring = "",
 ): ptr type T =
   var ret = createShared(T)
   ret[].operation = op
   ret[].peerId = peerId.alloc()
   ret[].peerAddresses = peerAddresses
+  ret[].cid = cid.alloc()
   return ret
 
 proc destroyShared(self: ptr NodeP2PRequest) =
   deallocShared(self[].peerId)
+  deallocShared(self[].cid)
   deallocShared(self)
 
 proc connect(
@@ -49,6 +73,9 @@ proc connect(
 
   let id = res.get()
 
+  if not codex[].peerGater.isAllowed(id):
+    return err("Failed to connect to peer: the peer is denied.")
+
   let addresses =
     if peerAddresses.len > 0:
       var addrs: seq[MultiAddress]
@@ -80,6 +107,147 @@ proc connect(
 
   return ok("")
 
+proc findProviders(
+    codex: ptr CodexServer, cCid: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Returns the peer records of the providers of the cid found in
+  ## the DHT, the node itself excluded.
+
+  let cid = Cid.init($cCid)
+  if cid.isErr:
+    return err("Failed to find providers: cannot parse cid: " & $cCid)
+
+  try:
+    let providers = await codex[].node.discovery.find(cid.get())
+
+    return ok($ %providers.mapIt(RestPeerRecord.init(it.data)))
+  except CancelledError:
+    return err("Failed to find providers: operation cancelled.")
+
+proc announce(
+    codex: ptr CodexServer, cCid: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Announces the cid in the DHT, without waiting for the advertiser.
+  ## The cid must be stored locally. For a manifest, the tree cid is
+  ## announced too, like the advertiser does, and the dataset is
+  ## published: the advertiser announces it from now on, even when it
+  ## was uploaded without announcement or the node is in private mode.
+
+  let cid = Cid.init($cCid)
+  if cid.isErr:
+    return err("Failed to announce: cannot parse cid: " & $cCid)
+
+  let node = codex[].node
+
+  try:
+    if not (await node.hasLocalBlock(cid.get())):
+      return err("Failed to announce: the cid is not stored locally: " & $cCid)
+
+    var cids = @[cid.get()]
+    if (cid.get().isManifest |? false):
+      without manifest =? await node.fetchManifest(cid.get()), error:
+        return err("Failed to announce: " & error.msg)
+
+      cids.add(manifest.treeCid)
+
+      publish(cid.get()).isOkOr:
+        return err("Failed to announce: " & error)
+
+    for c in cids:
+      await node.discovery.provide(c)
+  except CancelledError:
+    return err("Failed to announce: operation cancelled.")
+
+  return ok("")
+
+proc parsePeerId(peerId: cstring): Result[PeerId, string] =
+  let res = PeerId.init($peerId)
+  if res.isErr:
+    return err("invalid peer ID: " & $res.error())
+
+  return ok(res.get())
+
+proc allowPeer(
+    codex: ptr CodexServer, peerId: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  let id = parsePeerId(peerId).valueOr:
+    return err("Failed to allow peer: " & error)
+
+  codex[].peerGater.allow(id)
+
+  return ok("")
+
+proc denyPeer(
+    codex: ptr CodexServer, peerId: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  let id = parsePeerId(peerId).valueOr:
+    return err("Failed to deny peer: " & error)
+
+  try:
+    await codex[].peerGater.deny(id)
+  except CancelledError:
+    return err("Failed to deny peer: operation cancelled.")
+
+  return ok("")
+
+proc disconnect(
+    codex: ptr CodexServer, peerId: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Closes the connections of the peer, which can connect again
+  ## unless it is denied.
+
+  let id = parsePeerId(peerId).valueOr:
+    return err("Failed to disconnect peer: " & error)
+
+  try:
+    await codex[].node.switch.disconnect(id)
+  except CancelledError:
+    return err("Failed to disconnect peer: operation cancelled.")
+
+  return ok("")
+
+proc listPeers(
+    codex: ptr CodexServer
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Returns the peers connected to the node, with their addresses and
+  ## agent from the peer store and the counters of the block exchange.
+  ## The direction is "outbound" when the node has dialed the peer.
+
+  let node = codex[].node
+  let switch = node.switch
+  let outbound = switch.connectedPeers(Direction.Out).toHashSet
+  let inbound = switch.connectedPeers(Direction.In)
+
+  var peers: seq[JsonNode]
+  for peerId in concat(outbound.toSeq, inbound).deduplicate():
+    var
+      stats = BlockExcPeerStats()
+      pendingWants = 0
+      pendingRequests = 0
+
+    let peerCtx = node.engine.peers.get(peerId)
+    if not peerCtx.isNil:
+      stats = peerCtx.stats
+      pendingWants = peerCtx.wantedBlocks.len
+      pendingRequests = peerCtx.blocksRequested.len
+
+    peers.add(
+      %*{
+        "peerId": $peerId,
+        "addresses": switch.peerStore[AddressBook][peerId].mapIt($it),
+        "agent": switch.peerStore[AgentBook][peerId],
+        "direction": if peerId in outbound: "outbound" else: "inbound",
+        "blocksSent": stats.blocksSent,
+        "bytesSent": stats.bytesSent,
+        "blocksReceived": stats.blocksReceived,
+        "bytesReceived": stats.bytesReceived,
+        "pendingWants": pendingWants,
+        "pendingRequests": pendingRequests,
+      }
+    )
+
+  return ok($ %peers)
+
 proc process*(
     self: ptr NodeP2PRequest, codex: ptr CodexServer
 ): Future[Result[string, string]] {.async: (raises: []).} =
@@ -93,3 +261,39 @@ proc process*(
       error "Failed to CONNECT.", error = res.error
       return err($res.error)
     return res
+  of NodeP2PMsgType.FIND_PROVIDERS:
+    let res = (await findProviders(codex, self.cid))
+    if res.isErr:
+      error "Failed to FIND_PROVIDERS.", error = res.error
+      return err($res.error)
+    return res
+  of NodeP2PMsgType.ANNOUNCE:
+    let res = (await announce(codex, self.cid))
+    if res.isErr:
+      error "Failed to ANNOUNCE.", error = res.error
+      return err($res.error)
+    return res
+  of NodeP2PMsgType.ALLOW_PEER:
+    let res = (await allowPeer(codex, self.peerId))
+    if res.isErr:
+      error "Failed to ALLOW_PEER.", error = res.error
+      return err($res.error)
+    return res
+  of NodeP2PMsgType.DENY_PEER:
+    let res = (await denyPeer(codex, self.peerId))
+    if res.isErr:
+      error "Failed to DENY_PEER.", error = res.error
+      return err($res.error)
+    return res
+  of NodeP2PMsgType.DISCONNECT:
+    let res = (await disconnect(codex, self.peerId))
+    if res.isErr:
+      error "Failed to DISCONNECT.", error = res.error
+      return err($res.error)
+    return res
+  of NodeP2PMsgType.PEERS:
+    let res = (await listPeers(codex))
+    if res.isErr:
+      error "Failed to PEERS.", error = res.error
+      return err($res.error)
+    return res
@@ -1,22 +1,32 @@
 {.push raises: [].}
 
 ## This file contains the node storage request.
-## 4 operations are available:
+## 8 operations are available:
 ## - LIST: list all manifests stored in the node.
+## - LIST_PAGE: list a page of the manifests stored in the node, with a cursor.
 ## - DELETE: Deletes either a single block or an entire dataset from the local node.
 ## - FETCH: download a file from the network to the local node.
 ## - SPACE: get the amount of space used by the local node.
 ## - EXISTS: check the existence of a cid in a node (local store).
+## - SET_EXPIRY: set the expiry of every block of a dataset (local store).
+## - EXPIRY: get the earliest expiry of the blocks of a dataset (local store).
 
-import std/[options]
+import std/[algorithm, options, strutils]
 import chronos
 import chronicles
+import questionable
+import questionable/results
 import libp2p/stream/[lpstream]
 import serde/json as serde
+import datastore
 import ../../alloc
+import ../../dataset_expiry
 import ../../../codex/units
+import ../../../codex/clock
 import ../../../codex/manifest
 import ../../../codex/stores/repostore
+import ../../../codex/stores/blockstore
+import ../../../codex/stores/keyutils
 
 from ../../../codex/codex import CodexServer, node, repoStore
 from ../../../codex/node import
@@ -26,16 +36,27 @@ from libp2p import Cid, init, `$`
 logScope:
   topics = "codexlib codexlibstorage"
 
+## Number of manifests of a page when the limit is not set
+const DefaultListPageLimit = 100
+
 type NodeStorageMsgType* = enum
   LIST
+  LIST_PAGE
   DELETE
   FETCH
   SPACE
   EXISTS
+  SET_EXPIRY
+  EXPIRY
 
 type NodeStorageRequest* = object
   operation: NodeStorageMsgType
   cid: cstring
+  expiry: SecondsSince1970
+  cursor: cstring
+  limit: csize_t
+  mimetype: cstring
+  filenamePrefix: cstring
 
 type StorageSpace = object
   totalBlocks* {.serialize.}: Natural
@@ -44,22 +65,42 @@ type StorageSpace = object
   quotaReservedBytes* {.serialize.}: NBytes
 
 proc createShared*(
-    T: type NodeStorageRequest, op: NodeStorageMsgType, cid: cstring = ""
+    T: type NodeStorageRequest,
+    op: NodeStorageMsgType,
+    cid: cstring = "",
+    expiry: SecondsSince1970 = 0,
+    cursor: cstring = "",
+    limit: csize_t = 0,
+    mimetype: cstring = "",
+    filenamePrefix: cstring = "",
 ): ptr type T =
   var ret = createShared(T)
   ret[].operation = op
   ret[].cid = cid.alloc()
+  ret[].expiry = expiry
+  ret[].cursor = cursor.alloc()
+  ret[].limit = limit
+  ret[].mimetype = mimetype.alloc()
+  ret[].filenamePrefix = filenamePrefix.alloc()
 
   return ret
 
 proc destroyShared(self: ptr NodeStorageRequest) =
   deallocShared(self[].cid)
+  deallocShared(self[].cursor)
+  deallocShared(self[].mimetype)
+  deallocShared(self[].filenamePrefix)
   deallocShared(self)
 
 type ManifestWithCid = object
   cid {.serialize.}: string
   manifest {.serialize.}: Manifest
 
+type ManifestPage = object
+  items {.serialize.}: seq[ManifestWithCid]
+  # Cursor of the next page, empty on the last page
+  next {.serialize.}: string
+
 proc list(
     codex: ptr CodexServer
 ): Future[Result[string, string]] {.async: (raises: []).} =
@@ -77,6 +118,152 @@ proc list(
 
   return ok(serde.toJson(manifests))
 
+## Characters of the base58btc cids, in ASCII order
+const CidChars = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func nextBucket(bucket: string): ?string =
+  ## Returns the bucket following `bucket` in key order, none after the
+  ## last one.
+
+  var next = bucket
+  for i in countdown(next.high, 0):
+    let pos = CidChars.find(next[i])
+    if pos >= 0 and pos < CidChars.high:
+      next[i] = CidChars[pos + 1]
+      return some next
+
+    next[i] = CidChars[0]
+
+  return string.none
+
+proc bucketCids(
+    repoStore: RepoStore, bucket: string, cursor: string
+): Future[?!seq[Cid]] {.async: (raises: [CancelledError]).} =
+  ## Returns the cids of the manifests of the bucket following the
+  ## cursor, sorted. The manifest keys are grouped by the last
+  ## characters of their cid, see `makePrefixKey`, so a bucket holds a
+  ## small share of the manifests.
+
+  without bucketKey =? (CodexManifestKey / bucket), error:
+    return failure(error)
+
+  let query = Query.init(bucketKey, value = false)
+  without queryIter =? (await repoStore.repoDs.query(query)), error:
+    return failure(error)
+
+  var keys = newSeq[string]()
+  var failed: ?!void = success()
+  while not queryIter.finished:
+    without pair =? (await queryIter.next()), error:
+      failed = failure(error)
+      break
+
+    if key =? pair.key and key.value > cursor:
+      keys.add(key.value)
+
+  if error =? (await queryIter.dispose()).errorOption:
+    warn "Failed to dispose the manifests query", error = error.msg
+
+  if error =? failed.errorOption:
+    return failure(error)
+
+  keys.sort()
+
+  var cids = newSeq[Cid]()
+  for key in keys:
+    let cid = Cid.init(key)
+    if cid.isErr:
+      warn "Invalid manifest key", key
+      continue
+    cids.add(cid.get)
+
+  return success cids
+
+proc listPage(
+    codex: ptr CodexServer,
+    cCursor: cstring,
+    limit: csize_t,
+    cMimetype: cstring,
+    cFilenamePrefix: cstring,
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Returns up to `limit` manifests following the cursor, the cursor
+  ## of the first page is empty. The manifests can be filtered by
+  ## mimetype and filename prefix.
+  ##
+  ## The manifests are listed in the key order of the store: by the last
+  ## characters of their cid, then by cid. The cursor is the cid of the
+  ## last manifest of the previous page, the listing starts at its bucket
+  ## and stops once the page is full, so the manifests stored or deleted
+  ## between two pages do not shift the next ones.
+
+  var cursor = ""
+  if $cCursor != "":
+    let cid = Cid.init($cCursor)
+    if cid.isErr:
+      return err("Failed to list manifests: invalid cursor: " & $cCursor)
+
+    # The keys use the base58btc form of the cids
+    cursor = $cid.get
+
+  let limit = if limit > 0: limit.int else: DefaultListPageLimit
+  let mimetype = $cMimetype
+  let filenamePrefix = $cFilenamePrefix
+
+  var page = ManifestPage()
+
+  try:
+    let repoStore = codex[].repoStore
+    var bucket =
+      if cursor == "":
+        CidChars[0].repeat(repoStore.postFixLen)
+      else:
+        cursor[^repoStore.postFixLen ..^ 1]
+
+    # One more manifest than the limit tells whether a next page exists
+    var items = newSeq[ManifestWithCid]()
+    while items.len <= limit:
+      without cids =? await repoStore.bucketCids(bucket, cursor), error:
+        return err("Failed to list manifests: " & error.msg)
+
+      for cid in cids:
+        without blk =? await repoStore.getBlock(cid), error:
+          warn "Failed to get manifest block by cid", cid, error = error.msg
+          continue
+
+        without manifest =? Manifest.decode(blk), error:
+          warn "Failed to decode manifest", cid, error = error.msg
+          continue
+
+        if mimetype != "" and manifest.mimetype != mimetype.some:
+          continue
+
+        if filenamePrefix != "" and
+            not (manifest.filename |? "").startsWith(filenamePrefix):
+          continue
+
+        items.add(ManifestWithCid(cid: $cid, manifest: manifest))
+        if items.len > limit:
+          break
+
+      without following =? nextBucket(bucket):
+        break
+
+      bucket = following
+      # The following buckets only hold keys after the cursor
+      cursor = ""
+
+    if items.len > limit:
+      items.setLen(limit)
+      page.next = items[^1].cid
+
+    page.items = items
+  except CancelledError:
+    return err("Failed to list manifests: cancelled operation.")
+  except CatchableError as e:
+    return err("Failed to list manifests: " & e.msg)
+
+  return ok(serde.toJson(page))
+
 proc delete(
     codex: ptr CodexServer, cCid: cstring
 ): Future[Result[string, string]] {.async: (raises: []).} =
@@ -141,6 +328,41 @@ proc exists(
   except CancelledError:
     return err("Failed to check the data existence: operation cancelled.")
 
+proc setExpiry(
+    codex: ptr CodexServer, cCid: cstring, expiry: SecondsSince1970
+): Future[Result[string, string]] {.async: (raises: []).} =
+  let cid = Cid.init($cCid)
+  if cid.isErr:
+    return err("Failed to set the expiry: cannot parse cid: " & $cCid)
+
+  if expiry <= 0:
+    return err("Failed to set the expiry: the expiry must be larger than zero.")
+
+  try:
+    let res = await codex.setDatasetExpiry(cid.get(), expiry)
+    if res.isErr:
+      return err("Failed to set the expiry: " & res.error)
+
+    return ok("")
+  except CancelledError:
+    return err("Failed to set the expiry: operation cancelled.")
+
+proc expiry(
+    codex: ptr CodexServer, cCid: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  let cid = Cid.init($cCid)
+  if cid.isErr:
+    return err("Failed to get the expiry: cannot parse cid: " & $cCid)
+
+  try:
+    let res = await codex.datasetExpiry(cid.get())
+    if res.isErr:
+      return err("Failed to get the expiry: " & res.error)
+
+    return ok($res.get())
+  except CancelledError:
+    return err("Failed to get the expiry: operation cancelled.")
+
 proc process*(
     self: ptr NodeStorageRequest, codex: ptr CodexServer
 ): Future[Result[string, string]] {.async: (raises: []).} =
@@ -154,6 +376,16 @@ proc process*(
       error "Failed to LIST.", error = res.error
       return err($res.error)
     return res
+  of NodeStorageMsgType.LIST_PAGE:
+    let res = (
+      await listPage(
+        codex, self.cursor, self.limit, self.mimetype, self.filenamePrefix
+      )
+    )
+    if res.isErr:
+      error "Failed to LIST_PAGE.", error = res.error
+      return err($res.error)
+    return res
   of NodeStorageMsgType.DELETE:
     let res = (await delete(codex, self.cid))
     if res.isErr:
@@ -178,3 +410,15 @@ proc process*(
       error "Failed to EXISTS.", error = res.error
       return err($res.error)
     return res
+  of NodeStorageMsgType.SET_EXPIRY:
+    let res = (await setExpiry(codex, self.cid, self.expiry))
+    if res.isErr:
+      error "Failed to SET_EXPIRY.", error = res.error
+      return err($res.error)
+    return res
+  of NodeStorageMsgType.EXPIRY:
+    let res = (await expiry(codex, self.cid))
+    if res.isErr:
+      error "Failed to EXPIRY.", error = res.error
+      return err($res.error)
+    return res
@@ -14,21 +14,42 @@
 ## 2. Directly from a file path: the filepath has to be absolute.
 ##  - INIT: creates a new upload session and returns its ID
 ##  - FILE: starts the upload and returns the CID of the uploaded file
-##  - CANCEL: cancels the upload session.
+##  - FILE_RESULT: like FILE, returns in JSON the CID, the manifest and
+##    the SHA-256 of the file, computed while the file is read
+##  - CANCEL: cancels the upload session, the file read is stopped and
+##    FILE or FILE_RESULT returns an error containing UploadCancelledMsg.
+##
+## The blocks stored for each session are written to a journal in the data
+## dir. When the node is restarted during an upload, RESUME restores the
+## session from its journal and returns the number of bytes already stored,
+## the client sends the following chunks and FINALIZE. The interrupted
+## sessions expire after `uploadSessionTtl` without a stored block.
+##
+## LIST returns the active and interrupted sessions. The active sessions
+## without activity for `uploadSessionIdleTimeout` are cancelled.
+## An uploadSessionExpired event is emitted for each expired session.
 
-import std/[options, os, mimetypes]
+import std/[options, os, mimetypes, json, strutils]
 import chronos
 import chronicles
 import questionable
 import questionable/results
 import faststreams/inputs
 import libp2p/stream/[bufferstream, lpstream]
+import nimcrypto/[hash, sha2]
+import serde/json as serde
 import ../../alloc
+import ../../upload_journal
+import ../../announce_policy
+import ../../dataset_expiry
+import ../../events/[event_emitter, json_upload_event]
 import ../../../codex/units
 import ../../../codex/codextypes
+import ../../../codex/manifest
 
-from ../../../codex/codex import CodexServer, node
-from ../../../codex/node import store
+from ../../../codex/codex import CodexServer, node, config
+from ../../../codex/conf import CodexConf
+from ../../../codex/node import store, releaseBlocks, hasLocalBlock, fetchManifest
 from libp2p import Cid, `$`
 
 logScope:
@@ -40,15 +61,35 @@ type NodeUploadMsgType* = enum
   FINALIZE
   CANCEL
   FILE
+  FILE_RESULT
+  RESUME
+  LIST
 
 type OnProgressHandler = proc(bytes: int): void {.gcsafe, raises: [].}
 
+## Contained in the error returned when the upload is cancelled,
+## so that the clients can distinguish the cancellation from a failure.
+const UploadCancelledMsg* = "upload cancelled"
+
+## Interval between two checks of the idle sessions
+const IdleSessionsCheckInterval = 1.seconds
+
 type NodeUploadRequest* = object
   operation: NodeUploadMsgType
   sessionId: cstring
   filepath: cstring
   chunk: seq[byte]
   chunkSize: csize_t
+  filename: cstring
+  mimetype: cstring
+  ttl: int64
+  announce: bool
+
+type UploadedFile = object
+  cid {.serialize.}: string
+  manifest {.serialize.}: Manifest
+  # Hex encoded SHA-256 of the file
+  sha256 {.serialize.}: string
 
 type
   UploadSessionId* = string
@@ -58,10 +99,26 @@ type
     fut: Future[?!Cid]
     filepath: string
     chunkSize: int
+    # Expiry of the dataset after the finalization in seconds, 0 when not set
+    ttl: int64
     onProgress: OnProgressHandler
+    # Serializes the chunks sent concurrently to the session
+    lock: AsyncLock
+    # Reads the file when uploading directly from a file path
+    fileFut: FutureBase
+    # Number of blocks stored before the session was resumed
+    resumedBlocks: int
+    # Unix time of the creation of the session
+    createdAt: int64
+    # Bytes received, including the bytes stored before a resume
+    bytes: int
+    # Operations in progress, the session is not idle while they run
+    busy: int
+    lastActivity: Moment
 
 var uploadSessions {.threadvar.}: Table[UploadSessionId, UploadSession]
 var nexUploadSessionCount {.threadvar.}: UploadSessionCount
+var idleSessionsLoopStarted {.threadvar.}: bool
 
 proc createShared*(
     T: type NodeUploadRequest,
@@ -70,6 +127,10 @@ proc createShared*(
     filepath: cstring = "",
     chunk: seq[byte] = @[],
     chunkSize: csize_t = 0,
+    filename: cstring = "",
+    mimetype: cstring = "",
+    ttl: int64 = 0,
+    announce: bool = true,
 ): ptr type T =
   var ret = createShared(T)
   ret[].operation = op
@@ -77,63 +138,140 @@ proc createShared*(
   ret[].filepath = filepath.alloc()
   ret[].chunk = chunk
   ret[].chunkSize = chunkSize
+  ret[].filename = filename.alloc()
+  ret[].mimetype = mimetype.alloc()
+  ret[].ttl = ttl
+  ret[].announce = announce
 
   return ret
 
 proc destroyShared(self: ptr NodeUploadRequest) =
   deallocShared(self[].filepath)
   deallocShared(self[].sessionId)
+  deallocShared(self[].filename)
+  deallocShared(self[].mimetype)
   deallocShared(self)
 
-proc init(
-    codex: ptr CodexServer, filepath: cstring = "", chunkSize: csize_t = 0
-): Future[Result[string, string]] {.async: (raises: []).} =
-  ## Init a new session upload and return its ID.
-  ## The session contains the future corresponding to the
-  ## `node.store` call.
-  ## The filepath can be:
-  ##  - the filename when uploading via chunks
-  ##  - the absolute path to a file when uploading directly.
-  ## The mimetype is deduced from the filename extension.
-  ##
-  ## The chunkSize matches by default the block size used to store the file.
-  ##
-  ## A callback `onBlockStore` is provided to `node.store` to
-  ## report the progress of the upload. This callback will check
-  ## that an `onProgress` handler is set in the session
-  ## and call it with the number of bytes stored each time a block
-  ## is stored.
+proc journalDir(codex: ptr CodexServer): string =
+  string(codex[].config.dataDir) / UploadJournalDir
 
-  var filenameOpt, mimetypeOpt = string.none
+proc endSession(codex: ptr CodexServer, sessionId: string) =
+  ## Removes the session from the table and its journal.
+  if uploadSessions.contains(sessionId):
+    uploadSessions.del(sessionId)
 
-  if isAbsolute($filepath):
-    if not fileExists($filepath):
-      return err(
-        "Failed to create an upload session, the filepath does not exist: " & $filepath
+  codex.journalDir().remove(sessionId)
+
+proc touch(sessionId: string, bytes = 0) =
+  ## Records the activity of a session and the bytes received.
+  uploadSessions.withValue(sessionId, session):
+    session.bytes += bytes
+    session.lastActivity = Moment.now()
+
+proc setBusy(sessionId: string, busy: bool) =
+  ## Marks the start and the end of an operation on the session.
+  uploadSessions.withValue(sessionId, session):
+    if busy:
+      session.busy.inc()
+    else:
+      session.busy.dec()
+    session.lastActivity = Moment.now()
+
+proc expireSessions(codex: ptr CodexServer) {.async: (raises: []).} =
+  ## Releases the blocks and the journal of the interrupted sessions
+  ## which did not store any block for `uploadSessionTtl`.
+  ## A ttl of 0 disables the expiry.
+
+  let dir = codex.journalDir()
+  let ttl = codex[].config.uploadSessionTtl.seconds
+  if ttl <= 0:
+    return
+
+  for sessionId in dir.sessions():
+    if uploadSessions.contains(sessionId):
+      continue
+
+    let journal = dir.load(sessionId).valueOr:
+      continue
+
+    if nowUnix() - journal.updatedAt > ttl:
+      info "Upload session expired, releasing the stored blocks",
+        sessionId, blocks = journal.cids.len
+      await codex[].node.releaseBlocks(journal.cids)
+      dir.remove(sessionId)
+
+      emitEvent(
+        JsonUploadSessionExpiredEvent.new(
+          sessionId, journal.filepath, journal.cids.len * journal.chunkSize, "ttl"
+        )
       )
 
-  if filepath != "":
-    let (_, name, ext) = splitFile($filepath)
+proc fileMetadata(
+    filepath: string, filename: string, mimetype: string
+): tuple[filename: ?string, mimetype: ?string] =
+  ## Returns the filename and the mimetype stored in the manifest.
+  ## The filename defaults to the name of the filepath and the
+  ## mimetype is deduced from the extension of the filename.
+
+  let name = if filename != "": filename else: filepath
 
-    filenameOpt = (name & ext).some
+  if mimetype != "":
+    result.mimetype = mimetype.some
 
-    if ext != "":
-      let extNoDot =
-        if ext.len > 0:
-          ext[1 ..^ 1]
-        else:
-          ""
-      let mime = newMimetypes()
-      let mimetypeStr = mime.getMimetype(extNoDot, "")
+  if name == "":
+    return
 
-      mimetypeOpt = if mimetypeStr == "": string.none else: mimetypeStr.some
+  let (_, base, ext) = splitFile(name)
+  result.filename = (base & ext).some
 
-  let sessionId = $nexUploadSessionCount
-  nexUploadSessionCount.inc()
+  if result.mimetype.isSome:
+    return
+
+  if ext != "":
+    let extNoDot =
+      if ext.len > 0:
+        ext[1 ..^ 1]
+      else:
+        ""
+    let mime = newMimetypes()
+    let mimetypeStr = mime.getMimetype(extNoDot, "")
+
+    result.mimetype = if mimetypeStr == "": string.none else: mimetypeStr.some
+
+proc startIdleSessionsLoop(codex: ptr CodexServer)
+
+proc startSession(
+    codex: ptr CodexServer,
+    sessionId: string,
+    filepath: string,
+    filename: string,
+    mimetype: string,
+    blockSize: NBytes,
+    ttl: int64,
+    announce: bool,
+    createdAt: int64,
+    stored: seq[Cid] = @[],
+) =
+  ## Starts the `node.store` call of the session and adds the session
+  ## to the table. `stored` are the blocks of a resumed session.
+  ##
+  ## A callback `onBlockStore` is provided to `node.store` to
+  ## report the progress of the upload. This callback will check
+  ## that an `onProgress` handler is set in the session
+  ## and call it with the number of bytes stored each time a block
+  ## is stored.
+  ##
+  ## The cid of each block stored is appended to the journal of the session.
+  ##
+  ## Without announcement, the manifest is marked private before it is
+  ## stored, so the advertiser does not announce it, see `markPrivate`.
+
+  let (filenameOpt, mimetypeOpt) = fileMetadata(filepath, filename, mimetype)
 
   let stream = BufferStream.new()
   let lpStream = LPStream(stream)
   let node = codex[].node
+  let dir = codex.journalDir()
 
   let onBlockStored = proc(chunk: seq[byte]): void {.gcsafe, raises: [].} =
     try:
@@ -145,12 +283,107 @@ proc init(
       error "Failed to push progress update, session is not found: ",
         sessionId = $sessionId
 
+  let onBlockCid = proc(cid: Cid): void {.gcsafe, raises: [].} =
+    # The journal is removed when the session ends
+    if not uploadSessions.contains(sessionId):
+      return
+
+    let res = dir.append(sessionId, cid)
+    if res.isErr:
+      error "Failed to journal the stored block", sessionId, error = res.error
+
+  let onManifestCid = proc(cid: Cid): void {.gcsafe, raises: [].} =
+    if not announce:
+      markPrivate(cid)
+
+  let fut = node.store(
+    lpStream, filenameOpt, mimetypeOpt, blockSize, onBlockStored, stored, onBlockCid,
+    onManifestCid,
+  )
+
+  uploadSessions[sessionId] = UploadSession(
+    stream: stream,
+    fut: fut,
+    filepath: filepath,
+    chunkSize: blockSize.int,
+    ttl: ttl,
+    lock: newAsyncLock(),
+    resumedBlocks: stored.len,
+    createdAt: createdAt,
+    bytes: stored.len * blockSize.int,
+    lastActivity: Moment.now(),
+  )
+
+  codex.startIdleSessionsLoop()
+
+proc init(
+    codex: ptr CodexServer,
+    filepath: cstring = "",
+    chunkSize: csize_t = 0,
+    filename: cstring = "",
+    mimetype: cstring = "",
+    ttl: int64 = 0,
+    announce: bool = true,
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Init a new session upload and return its ID.
+  ## The session contains the future corresponding to the
+  ## `node.store` call.
+  ## The filepath can be:
+  ##  - the filename when uploading via chunks
+  ##  - the absolute path to a file when uploading directly.
+  ## The filename and the mimetype stored in the manifest can be
+  ## overridden, by default the mimetype is deduced from the
+  ## filename extension.
+  ##
+  ## When the ttl is larger than zero, the expiry of the dataset is set
+  ## to ttl seconds after the finalization, see `setDatasetExpiry`.
+  ##
+  ## Without announcement, the dataset is not announced to the network
+  ## until it is published with ANNOUNCE, see `announce_policy`.
+  ##
+  ## The chunkSize matches by default the block size used to store the file.
+  ##
+  ## The journal of the session is created, and the expired sessions
+  ## are released.
+
+  if isAbsolute($filepath):
+    if not fileExists($filepath):
+      return err(
+        "Failed to create an upload session, the filepath does not exist: " & $filepath
+      )
+
+  await codex.expireSessions()
+
+  let dir = codex.journalDir()
+
+  # The ids of the sessions interrupted by a restart are kept for RESUME
+  var sessionId = $nexUploadSessionCount
+  nexUploadSessionCount.inc()
+  while dir.exists(sessionId):
+    sessionId = $nexUploadSessionCount
+    nexUploadSessionCount.inc()
+
   let blockSize =
     if chunkSize.NBytes > 0.NBytes: chunkSize.NBytes else: DefaultBlockSize
-  let fut = node.store(lpStream, filenameOpt, mimetypeOpt, blockSize, onBlockStored)
 
-  uploadSessions[sessionId] = UploadSession(
-    stream: stream, fut: fut, filepath: $filepath, chunkSize: blockSize.int
+  let createdAt = nowUnix()
+  let res = dir.write(
+    sessionId,
+    UploadJournal(
+      filepath: $filepath,
+      filename: $filename,
+      mimetype: $mimetype,
+      chunkSize: blockSize.int,
+      ttl: ttl,
+      announce: announce,
+      createdAt: createdAt,
+    ),
+  )
+  if res.isErr:
+    return err("Failed to create an upload session: " & res.error)
+
+  codex.startSession(
+    sessionId, $filepath, $filename, $mimetype, blockSize, ttl, announce, createdAt
   )
 
   return ok(sessionId)
@@ -170,6 +403,36 @@ proc chunk(
   ## The wrapper may then report the progress because the data is in the stream
   ## but not yet stored.
 
+  if not uploadSessions.contains($sessionId):
+    return err("Failed to upload the chunk, the session is not found: " & $sessionId)
+
+  var lock: AsyncLock
+  try:
+    lock = uploadSessions[$sessionId].lock
+  except KeyError:
+    return err("Failed to upload the chunk, the session is not found: " & $sessionId)
+
+  # The session is not idle while the chunk waits for the lock
+  setBusy($sessionId, true)
+  defer:
+    setBusy($sessionId, false)
+
+  ## Several chunks can be sent without waiting for the previous ones.
+  ## They are pushed to the stream one at a time, in the order they were
+  ## received: the lock is acquired before the first await of this proc
+  ## and its waiters are resumed in FIFO order.
+  try:
+    await lock.acquire()
+  except CancelledError:
+    return err("Failed to upload the chunk, operation cancelled.")
+
+  defer:
+    try:
+      lock.release()
+    except AsyncLockError as e:
+      error "Failed to release the upload session lock", error = e.msg
+
+  # The session may have been cancelled while waiting for the lock
   if not uploadSessions.contains($sessionId):
     return err("Failed to upload the chunk, the session is not found: " & $sessionId)
 
@@ -190,6 +453,7 @@ proc chunk(
     await fut
 
     uploadSessions[$sessionId].onProgress = nil
+    touch($sessionId, chunk.len)
   except KeyError:
     return err("Failed to upload the chunk, the session is not found: " & $sessionId)
   except LPError as e:
@@ -204,69 +468,214 @@ proc chunk(
 
   return ok("")
 
-proc finalize(
+proc finalizeSession(
     codex: ptr CodexServer, sessionId: cstring
-): Future[Result[string, string]] {.async: (raises: []).} =
+): Future[Result[Cid, string]] {.async: (raises: []).} =
   ## Finalize the upload session identified by sessionId.
   ## This closes the BufferStream and waits for the `node.store` future
   ## to complete. It returns the CID of the uploaded file.
+  ## The ttl of the session, if any, is then applied to the dataset.
 
   if not uploadSessions.contains($sessionId):
     return
       err("Failed to finalize the upload session, session not found: " & $sessionId)
 
   var session: UploadSession
+  var locked = false
   try:
     session = uploadSessions[$sessionId]
+    # The session is removed when the finalization ends
+    setBusy($sessionId, true)
+
+    # Wait for the chunks still in flight
+    await session.lock.acquire()
+    locked = true
+
     await session.stream.pushEof()
 
     let res = await session.fut
     if res.isErr:
       return err("Failed to finalize the upload session: " & res.error().msg)
 
-    return ok($res.get())
+    let cid = res.get()
+    if session.ttl > 0:
+      let expiry = await codex.setDatasetExpiry(cid, nowUnix() + session.ttl)
+      if expiry.isErr:
+        return err(
+          "Failed to finalize the upload session, cannot set the expiry of " & $cid &
+            ": " & expiry.error
+        )
+
+    return ok(cid)
   except KeyError:
     return
       err("Failed to finalize the upload session, invalid session ID: " & $sessionId)
   except LPStreamError as e:
     return err("Failed to finalize the upload session, stream error: " & $e.msg)
   except CancelledError:
-    return err("Failed to finalize the upload session, operation cancelled")
+    return err("Failed to finalize the upload session: " & UploadCancelledMsg)
   except CatchableError as e:
     return err("Failed to finalize the upload session: " & $e.msg)
   finally:
-    if uploadSessions.contains($sessionId):
-      uploadSessions.del($sessionId)
+    codex.endSession($sessionId)
 
     if session.fut != nil and not session.fut.finished():
       session.fut.cancelSoon()
 
+    # The chunks waiting for the lock fail, the session is deleted
+    if locked:
+      try:
+        session.lock.release()
+      except AsyncLockError as e:
+        error "Failed to release the upload session lock", error = e.msg
+
+proc finalize(
+    codex: ptr CodexServer, sessionId: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  let cid = ?(await codex.finalizeSession(sessionId))
+  return ok($cid)
+
+proc describe(
+    codex: ptr CodexServer, cid: Cid, digest: string
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Returns the uploaded file in JSON. The manifest was just stored,
+  ## it is read from the local store.
+
+  try:
+    let manifest = await codex[].node.fetchManifest(cid)
+    if manifest.isErr:
+      return err("Failed to read the manifest of " & $cid & ": " & manifest.error.msg)
+
+    let uploaded = UploadedFile(cid: $cid, manifest: manifest.get(), sha256: digest)
+    return ok(serde.toJson(uploaded))
+  except CancelledError:
+    return err("Failed to read the manifest of " & $cid & ": " & UploadCancelledMsg)
+
 proc cancel(
     codex: ptr CodexServer, sessionId: cstring
 ): Future[Result[string, string]] {.async: (raises: []).} =
   ## Cancel the upload session identified by sessionId.
   ## This cancels the `node.store` future and removes the session
   ## from the table.
+  ## The blocks of a session interrupted by a restart are released.
 
   if not uploadSessions.contains($sessionId):
-    # Session not found, nothing to cancel
+    let dir = codex.journalDir()
+    let journal = dir.load($sessionId).valueOr:
+      # Session not found, nothing to cancel
+      return ok("")
+
+    await codex[].node.releaseBlocks(journal.cids)
+    dir.remove($sessionId)
+
     return ok("")
 
   try:
     let session = uploadSessions[$sessionId]
+
+    # Stop reading the file first, then node.store, which releases
+    # the blocks already stored
+    if not session.fileFut.isNil:
+      session.fileFut.cancelSoon()
+
     session.fut.cancelSoon()
   except KeyError:
     # Session not found, nothing to cancel
     return ok("")
 
-  uploadSessions.del($sessionId)
+  codex.endSession($sessionId)
 
   return ok("")
 
+proc cancelIdleSessions(codex: ptr CodexServer) {.async: (raises: []).} =
+  ## Cancels the sessions without activity for `uploadSessionIdleTimeout`,
+  ## abandoned by their client. The sessions with an operation in
+  ## progress are not idle.
+
+  let timeout = codex[].config.uploadSessionIdleTimeout
+  if timeout.seconds <= 0:
+    return
+
+  let now = Moment.now()
+  var idle: seq[tuple[sessionId: string, filepath: string, bytes: int]]
+  for sessionId, session in uploadSessions:
+    if session.busy == 0 and now - session.lastActivity > timeout:
+      idle.add((sessionId, session.filepath, session.bytes))
+
+  for (sessionId, filepath, bytes) in idle:
+    warn "Upload session idle, cancelling it", sessionId, timeout
+
+    let res = await codex.cancel(sessionId.cstring)
+    if res.isErr:
+      error "Failed to cancel the idle upload session", sessionId, error = res.error
+      continue
+
+    emitEvent(JsonUploadSessionExpiredEvent.new(sessionId, filepath, bytes, "idle"))
+
+proc idleSessionsLoop(codex: ptr CodexServer) {.async: (raises: []).} =
+  while true:
+    try:
+      await sleepAsync(IdleSessionsCheckInterval)
+    except CancelledError:
+      return
+
+    await codex.cancelIdleSessions()
+
+proc startIdleSessionsLoop(codex: ptr CodexServer) =
+  ## Starts the check of the idle sessions, once per Codex thread.
+  if not idleSessionsLoopStarted:
+    idleSessionsLoopStarted = true
+    asyncSpawn codex.idleSessionsLoop()
+
+proc list(codex: ptr CodexServer): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Returns the upload sessions in JSON: the active sessions and the
+  ## sessions interrupted by a restart, which can be resumed.
+  ## The bytes of an interrupted session are the bytes stored.
+
+  await codex.expireSessions()
+
+  var sessions = newJArray()
+  for sessionId, session in uploadSessions:
+    sessions.add(
+      %*{
+        "sessionId": sessionId,
+        "filepath": session.filepath,
+        "bytes": session.bytes,
+        "createdAt": session.createdAt,
+        "interrupted": false,
+      }
+    )
+
+  let dir = codex.journalDir()
+  for sessionId in dir.sessions():
+    if uploadSessions.contains(sessionId):
+      continue
+
+    let journal = dir.load(sessionId).valueOr:
+      continue
+
+    sessions.add(
+      %*{
+        "sessionId": sessionId,
+        "filepath": journal.filepath,
+        "bytes": journal.cids.len * journal.chunkSize,
+        "createdAt": journal.createdAt,
+        "interrupted": true,
+      }
+    )
+
+  return ok($sessions)
+
 proc streamFile(
-    filepath: string, stream: BufferStream, chunkSize: int
-): Future[Result[void, string]] {.async: (raises: [CancelledError]).} =
+    sessionId: string,
+    filepath: string,
+    stream: BufferStream,
+    chunkSize: int,
+    hashed: bool,
+): Future[Result[string, string]] {.async: (raises: [CancelledError]).} =
   ## Streams a file from the given filepath using faststream.
+  ## When `hashed` is set, it returns the hex encoded SHA-256 of the
+  ## file, computed while the file is read, otherwise an empty string.
   ## fsMultiSync cannot be used with chronos because of this warning:
   ## Warning: chronos backend uses nested calls to `waitFor` which
   ## is not supported by chronos - it is not recommended to use it until
@@ -279,24 +688,40 @@ proc streamFile(
     let inputStreamHandle = filepath.fileInput()
     let inputStream = inputStreamHandle.implicitDeref
 
+    var hasher: sha256
+    if hashed:
+      hasher.init()
+
     var buf = newSeq[byte](chunkSize)
     while inputStream.readable:
       let read = inputStream.readIntoEx(buf)
       if read == 0:
         break
+      if hashed:
+        hasher.update(buf.toOpenArray(0, read - 1))
       await stream.pushData(buf[0 ..< read])
+      touch(sessionId, read)
       # let byt = inputStream.read
       # await stream.pushData(@[byt])
-    return ok()
+
+    if not hashed:
+      return ok("")
+
+    return ok(toLowerAscii($hasher.finish()))
   except IOError, OSError, LPStreamError:
     let e = getCurrentException()
     return err("Failed to stream the file: " & $e.msg)
 
 proc file(
-    codex: ptr CodexServer, sessionId: cstring, onProgress: OnProgressHandler
+    codex: ptr CodexServer,
+    sessionId: cstring,
+    onProgress: OnProgressHandler,
+    described = false,
 ): Future[Result[string, string]] {.async: (raises: []).} =
   ## Starts the file upload for the session identified by sessionId.
   ## Will call finalize when done and return the CID of the uploaded file.
+  ## When `described` is set, it returns the CID, the manifest and the
+  ## SHA-256 of the file in JSON instead, see `UploadedFile`.
   ##
   ## The onProgress callback is called with the number of bytes
   ## to report the progress of the upload.
@@ -304,33 +729,123 @@ proc file(
   if not uploadSessions.contains($sessionId):
     return err("Failed to upload the file, invalid session ID: " & $sessionId)
 
+  # Rejected before the session is ended below: a resumed session keeps
+  # its journal and its stored blocks, to be resumed with chunks
+  var resumed = false
+  uploadSessions.withValue($sessionId, s):
+    resumed = s.resumedBlocks > 0
+
+  if resumed:
+    return err("Failed to upload the file, a resumed session takes chunks.")
+
   var session: UploadSession
 
   try:
     uploadSessions[$sessionId].onProgress = onProgress
     session = uploadSessions[$sessionId]
 
-    let res = await streamFile(session.filepath, session.stream, session.chunkSize)
+    # The session is removed when the upload ends
+    setBusy($sessionId, true)
+
+    let fileFut = streamFile(
+      $sessionId, session.filepath, session.stream, session.chunkSize, described
+    )
+    uploadSessions[$sessionId].fileFut = fileFut
+
+    let res = await fileFut
     if res.isErr:
+      if session.fut.cancelled():
+        return err("Failed to upload the file: " & UploadCancelledMsg)
+
       return err("Failed to upload the file: " & res.error)
 
-    return await codex.finalize(sessionId)
+    if not described:
+      return await codex.finalize(sessionId)
+
+    let cid = ?(await codex.finalizeSession(sessionId))
+    return await codex.describe(cid, res.get())
   except KeyError:
     return err("Failed to upload the file, the session is not found: " & $sessionId)
   except LPStreamError, IOError:
     let e = getCurrentException()
     return err("Failed to upload the file: " & $e.msg)
   except CancelledError:
-    return err("Failed to upload the file, the operation is cancelled.")
+    return err("Failed to upload the file: " & UploadCancelledMsg)
   except CatchableError as e:
     return err("Failed to upload the file: " & $e.msg)
   finally:
-    if uploadSessions.contains($sessionId):
-      uploadSessions.del($sessionId)
+    codex.endSession($sessionId)
 
     if session.fut != nil and not session.fut.finished():
       session.fut.cancelSoon()
 
+proc resume(
+    codex: ptr CodexServer, sessionId: cstring
+): Future[Result[string, string]] {.async: (raises: []).} =
+  ## Resume the upload session identified by sessionId, interrupted
+  ## by a restart of the node.
+  ## The blocks of the journal are checked in order, the first one
+  ## missing from the store is dropped with the following ones.
+  ## A new `node.store` call continues after the remaining blocks.
+  ##
+  ## It returns the session in JSON, with the number of bytes
+  ## already stored: the client sends the data following them.
+
+  if uploadSessions.contains($sessionId):
+    return
+      err("Failed to resume the upload session, the session is active: " & $sessionId)
+
+  await codex.expireSessions()
+
+  let dir = codex.journalDir()
+  var journal = dir.load($sessionId).valueOr:
+    return err("Failed to resume the upload session, " & error)
+
+  if journal.chunkSize <= 0:
+    return err("Failed to resume the upload session, invalid chunk size in the journal.")
+
+  var stored: seq[Cid]
+  try:
+    for cid in journal.cids:
+      if not (await codex[].node.hasLocalBlock(cid)):
+        warn "Block of the upload session not found, resuming before it",
+          sessionId = $sessionId, cid
+        break
+
+      stored.add(cid)
+  except CancelledError:
+    return err("Failed to resume the upload session, operation cancelled.")
+
+  journal.cids = stored
+  let res = dir.write($sessionId, journal)
+  if res.isErr:
+    return err("Failed to resume the upload session: " & res.error)
+
+  codex.startSession(
+    $sessionId,
+    journal.filepath,
+    journal.filename,
+    journal.mimetype,
+    journal.chunkSize.NBytes,
+    journal.ttl,
+    journal.announce,
+    journal.createdAt,
+    stored,
+  )
+
+  info "Upload session resumed", sessionId = $sessionId, blocks = stored.len
+
+  return ok(
+    $(
+      %*{
+        "sessionId": $sessionId,
+        "filepath": journal.filepath,
+        "chunkSize": journal.chunkSize,
+        "bytes": stored.len * journal.chunkSize,
+      }
+    )
+  )
+
 proc process*(
     self: ptr NodeUploadRequest,
     codex: ptr CodexServer,
@@ -341,7 +856,13 @@ proc process*(
 
   case self.operation
   of NodeUploadMsgType.INIT:
-    let res = (await init(codex, self.filepath, self.chunkSize))
+    let res =
+      (
+        await init(
+          codex, self.filepath, self.chunkSize, self.filename, self.mimetype, self.ttl,
+          self.announce,
+        )
+      )
     if res.isErr:
       error "Failed to INIT.", error = res.error
       return err($res.error)
@@ -370,3 +891,21 @@ proc process*(
       error "Failed to FILE.", error = res.error
       return err($res.error)
     return res
+  of NodeUploadMsgType.FILE_RESULT:
+    let res = (await file(codex, self.sessionId, onUploadProgress, described = true))
+    if res.isErr:
+      error "Failed to FILE_RESULT.", error = res.error
+      return err($res.error)
+    return res
+  of NodeUploadMsgType.RESUME:
+    let res = (await resume(codex, self.sessionId))
+    if res.isErr:
+      error "Failed to RESUME.", error = res.error
+      return err($res.error)
+    return res
+  of NodeUploadMsgType.LIST:
+    let res = (await list(codex))
+    if res.isErr:
+      error "Failed to LIST.", error = res.error
+      return err($res.error)
+    return res
@@ -0,0 +1,109 @@
+{.push raises: [].}
+
+## Expiry of the datasets stored in the node.
+##
+## The expiry of a dataset is the expiry of its blocks: the manifest
+## block and the blocks of its tree. The blocks not stored locally,
+## for a dataset partially fetched, are skipped. The maintenance of
+## the repo deletes the blocks once their expiry is reached.
+##
+## The blocks are deduplicated: a block shared with other datasets
+## keeps the latest expiry, so these datasets are not expired sooner.
+
+import chronos
+import chronicles
+import questionable
+import questionable/results
+import results
+import ../codex/clock
+import ../codex/manifest
+import ../codex/stores/repostore
+
+from ../codex/codex import CodexServer, repoStore
+from libp2p import Cid, `$`
+
+logScope:
+  topics = "codexlib codexlibexpiry"
+
+proc localManifest(
+    repoStore: RepoStore, cid: Cid
+): Future[Result[?Manifest, string]] {.async: (raises: [CancelledError]).} =
+  ## Returns the manifest of the cid, none when the cid is a single block.
+  ## The manifest block must be stored locally.
+
+  without isManifest =? cid.isManifest, error:
+    return err("invalid cid: " & error.msg)
+
+  if not isManifest:
+    return ok(Manifest.none)
+
+  without blk =? await repoStore.getBlock(cid), error:
+    return err("the manifest is not found locally: " & error.msg)
+
+  without manifest =? Manifest.decode(blk), error:
+    return err("failed to decode the manifest: " & error.msg)
+
+  return ok(manifest.some)
+
+proc setDatasetExpiry*(
+    codex: ptr CodexServer, cid: Cid, expiry: SecondsSince1970
+): Future[Result[int, string]] {.async: (raises: [CancelledError]).} =
+  ## Sets the expiry of the dataset, even if it is sooner than the
+  ## current one, except for the blocks shared with other datasets.
+  ## It returns the number of blocks updated.
+
+  let repoStore = codex[].repoStore
+
+  let maybeManifest = (await repoStore.localManifest(cid)).valueOr:
+    return err(error)
+
+  if e =? (await repoStore.setExpiry(cid, expiry)).errorOption:
+    return err("failed to set the expiry of " & $cid & ": " & e.msg)
+
+  var updated = 1
+
+  if manifest =? maybeManifest:
+    for index in 0 ..< manifest.blocksCount:
+      if e =? (await repoStore.setExpiry(manifest.treeCid, index, expiry)).errorOption:
+        if e of BlockNotFoundError:
+          continue
+
+        return err(
+          "failed to set the expiry of block " & $index & " of " & $cid & ": " & e.msg
+        )
+
+      updated.inc()
+
+  trace "Dataset expiry set", cid, expiry, blocks = updated
+
+  return ok(updated)
+
+proc datasetExpiry*(
+    codex: ptr CodexServer, cid: Cid
+): Future[Result[SecondsSince1970, string]] {.async: (raises: [CancelledError]).} =
+  ## Returns the earliest expiry of the blocks of the dataset stored
+  ## locally, when the dataset starts to be deleted.
+
+  let repoStore = codex[].repoStore
+
+  let maybeManifest = (await repoStore.localManifest(cid)).valueOr:
+    return err(error)
+
+  without manifestExpiry =? await repoStore.getExpiry(cid), e:
+    return err("failed to get the expiry of " & $cid & ": " & e.msg)
+
+  var expiry = manifestExpiry
+
+  if manifest =? maybeManifest:
+    for index in 0 ..< manifest.blocksCount:
+      without blockExpiry =? await repoStore.getExpiry(manifest.treeCid, index), e:
+        if e of BlockNotFoundError:
+          continue
+
+        return err(
+          "failed to get the expiry of block " & $index & " of " & $cid & ": " & e.msg
+        )
+
+      expiry = min(expiry, blockExpiry)
+
+  return ok(expiry)
@@ -0,0 +1,25 @@
+{.push raises: [].}
+
+## The events are emitted from the Codex thread of a context:
+## the thread sets the emitter forwarding them to the event
+## callback of its context when it starts.
+
+import chronicles
+import ./json_base_event
+
+type EventEmitter* = proc(event: string) {.gcsafe, raises: [].}
+
+var eventEmitter {.threadvar.}: EventEmitter
+
+proc setEventEmitter*(emitter: EventEmitter) =
+  eventEmitter = emitter
+
+proc emitEvent*(event: JsonEvent) =
+  ## Sends the event to the client of the context of the current thread.
+  if eventEmitter.isNil:
+    return
+
+  try:
+    eventEmitter($event)
+  except Exception as e:
+    error "Failed to emit the event", eventType = event.eventType, error = e.msg
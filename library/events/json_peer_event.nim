@@ -0,0 +1,19 @@
+{.push raises: [].}
+
+import std/json
+import ./json_base_event
+
+type JsonPeerRejectedEvent* = ref object of JsonEvent
+  peerId*: string
+  # "inbound" for a connection of the peer, "outbound" for a dial
+  direction*: string
+
+proc new*(T: type JsonPeerRejectedEvent, peerId: string, incoming: bool): T =
+  return JsonPeerRejectedEvent(
+    eventType: "peerRejected",
+    peerId: peerId,
+    direction: if incoming: "inbound" else: "outbound",
+  )
+
+method `$`*(event: JsonPeerRejectedEvent): string =
+  $(%*event)
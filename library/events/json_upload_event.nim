@@ -0,0 +1,30 @@
+{.push raises: [].}
+
+import std/json
+import ./json_base_event
+
+type JsonUploadSessionExpiredEvent* = ref object of JsonEvent
+  sessionId*: string
+  filepath*: string
+  bytes*: int
+  # "idle" for an active session without activity,
+  # "ttl" for an interrupted session which was not resumed
+  reason*: string
+
+proc new*(
+    T: type JsonUploadSessionExpiredEvent,
+    sessionId: string,
+    filepath: string,
+    bytes: int,
+    reason: string,
+): T =
+  return JsonUploadSessionExpiredEvent(
+    eventType: "uploadSessionExpired",
+    sessionId: sessionId,
+    filepath: filepath,
+    bytes: bytes,
+    reason: reason,
+  )
+
+method `$`*(event: JsonUploadSessionExpiredEvent): string =
+  $(%*event)
@@ -11,6 +11,7 @@
 #ifndef __libcodex__
 #define __libcodex__
 
+#include <stdbool.h>
 #include <stddef.h>
 #include <stdint.h>
 
@@ -75,6 +76,41 @@ int codex_connect(
                 CodexCallback callback,
                 void* userData);
 
+int codex_find_providers(
+                void* ctx,
+                const char* cid,
+                CodexCallback callback,
+                void* userData);
+
+int codex_announce(
+                void* ctx,
+                const char* cid,
+                CodexCallback callback,
+                void* userData);
+
+int codex_allow_peer(
+                void* ctx,
+                const char* peerId,
+                CodexCallback callback,
+                void* userData);
+
+int codex_deny_peer(
+                void* ctx,
+                const char* peerId,
+                CodexCallback callback,
+                void* userData);
+
+int codex_disconnect(
+                void* ctx,
+                const char* peerId,
+                CodexCallback callback,
+                void* userData);
+
+int codex_peers(
+                void* ctx,
+                CodexCallback callback,
+                void* userData);
+
 int codex_peer_debug(
                 void* ctx,
                 const char* peerId,
@@ -86,6 +122,10 @@ int codex_upload_init(
                 void* ctx,
                 const char* filepath,
                 size_t chunkSize,
+                const char* filename,
+                const char* mimetype,
+                int64_t ttl,
+                bool announce,
                 CodexCallback callback,
                 void* userData);
 
@@ -115,6 +155,23 @@ int codex_upload_file(
                 CodexCallback callback,
                 void* userData);
 
+int codex_upload_file_result(
+                void* ctx,
+                const char* sessionId,
+                CodexCallback callback,
+                void* userData);
+
+int codex_upload_resume(
+                void* ctx,
+                const char* sessionId,
+                CodexCallback callback,
+                void* userData);
+
+int codex_upload_list(
+                void* ctx,
+                CodexCallback callback,
+                void* userData);
+
 int codex_download_stream(
                 void* ctx,
                 const char* cid,
@@ -155,6 +212,15 @@ int codex_storage_list(
                 CodexCallback callback,
                 void* userData);
 
+int codex_storage_list_page(
+                void* ctx,
+                const char* cursor,
+                size_t limit,
+                const char* mimetype,
+                const char* filenamePrefix,
+                CodexCallback callback,
+                void* userData);
+
 int codex_storage_space(
                 void* ctx,
                 CodexCallback callback,
@@ -178,6 +244,19 @@ int codex_storage_exists(
                 CodexCallback callback,
                 void* userData);
 
+int codex_storage_set_expiry(
+                void* ctx,
+                const char* cid,
+                int64_t expiry,
+                CodexCallback callback,
+                void* userData);
+
+int codex_storage_expiry(
+                void* ctx,
+                const char* cid,
+                CodexCallback callback,
+                void* userData);
+
 int codex_start(void* ctx,
                CodexCallback callback,
                void* userData);
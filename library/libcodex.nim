@@ -59,8 +59,15 @@ template checkLibcodexParams*(
 # "Use --nimMainPrefix:MyLib and the function to call is named MyLibNimMain."
 proc libcodexNimMain() {.importc.}
 
-# Atomic flag to prevent multiple initializations
-var initialized: Atomic[bool]
+# Initialization state of the Nim runtime, shared by all the contexts
+# of the process. It is an atomic so that it does not depend on the
+# module initialization, which is done by libcodexNimMain itself.
+const
+  NotInitialized = 0
+  Initializing = 1
+  Initialized = 2
+
+var initState: Atomic[int]
 
 if defined(android):
   # Redirect chronicles to Android System logs
@@ -70,11 +77,19 @@ if defined(android):
     ) {.raises: [].} =
       echo logLevel, msg
 
-# Initializes the Nim runtime and foreign-thread GC
+# Initializes the Nim runtime and foreign-thread GC.
+# Several contexts can be created concurrently from different threads:
+# the first caller runs `libcodexNimMain` and the others wait for it
+# to complete before using the runtime.
 proc initializeLibrary() {.exported.} =
-  if not initialized.exchange(true):
+  var expected = NotInitialized
+  if initState.compareExchange(expected, Initializing):
     ## Every Nim library must call `<prefix>NimMain()` once
     libcodexNimMain()
+    initState.store(Initialized)
+  else:
+    while initState.load() != Initialized:
+      cpuRelax()
   when declared(setupForeignThreadGc):
     setupForeignThreadGc()
   when declared(nimGC_setStackBottom):
@@ -234,6 +249,88 @@ proc codex_connect(
 
   return callback.okOrError(res, userData)
 
+proc codex_find_providers(
+    ctx: ptr CodexContext, cid: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent =
+    NodeP2PRequest.createShared(NodeP2PMsgType.FIND_PROVIDERS, cid = cid)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_announce(
+    ctx: ptr CodexContext, cid: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent = NodeP2PRequest.createShared(NodeP2PMsgType.ANNOUNCE, cid = cid)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_allow_peer(
+    ctx: ptr CodexContext, peerId: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent =
+    NodeP2PRequest.createShared(NodeP2PMsgType.ALLOW_PEER, peerId = peerId)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_deny_peer(
+    ctx: ptr CodexContext, peerId: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent =
+    NodeP2PRequest.createShared(NodeP2PMsgType.DENY_PEER, peerId = peerId)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_disconnect(
+    ctx: ptr CodexContext, peerId: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent =
+    NodeP2PRequest.createShared(NodeP2PMsgType.DISCONNECT, peerId = peerId)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_peers(
+    ctx: ptr CodexContext, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent = NodeP2PRequest.createShared(NodeP2PMsgType.PEERS)
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.P2P, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
 proc codex_peer_debug(
     ctx: ptr CodexContext, peerId: cstring, callback: CodexCallback, userData: pointer
 ): cint {.dynlib, exportc.} =
@@ -278,6 +375,10 @@ proc codex_upload_init(
     ctx: ptr CodexContext,
     filepath: cstring,
     chunkSize: csize_t,
+    filename: cstring,
+    mimetype: cstring,
+    ttl: int64,
+    announce: bool,
     callback: CodexCallback,
     userData: pointer,
 ): cint {.dynlib, exportc.} =
@@ -285,7 +386,13 @@ proc codex_upload_init(
   checkLibcodexParams(ctx, callback, userData)
 
   let reqContent = NodeUploadRequest.createShared(
-    NodeUploadMsgType.INIT, filepath = filepath, chunkSize = chunkSize
+    NodeUploadMsgType.INIT,
+    filepath = filepath,
+    chunkSize = chunkSize,
+    filename = filename,
+    mimetype = mimetype,
+    ttl = ttl,
+    announce = announce,
   )
 
   let res = codex_context.sendRequestToCodexThread(
@@ -352,6 +459,38 @@ proc codex_upload_cancel(
 
   return callback.okOrError(res, userData)
 
+proc codex_upload_resume(
+    ctx: ptr CodexContext,
+    sessionId: cstring,
+    callback: CodexCallback,
+    userData: pointer,
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent =
+    NodeUploadRequest.createShared(NodeUploadMsgType.RESUME, sessionId = sessionId)
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.UPLOAD, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_upload_list(
+    ctx: ptr CodexContext, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent = NodeUploadRequest.createShared(NodeUploadMsgType.LIST)
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.UPLOAD, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
 proc codex_upload_file(
     ctx: ptr CodexContext,
     sessionId: cstring,
@@ -370,6 +509,25 @@ proc codex_upload_file(
 
   return callback.okOrError(res, userData)
 
+proc codex_upload_file_result(
+    ctx: ptr CodexContext,
+    sessionId: cstring,
+    callback: CodexCallback,
+    userData: pointer,
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let reqContent = NodeUploadRequest.createShared(
+    NodeUploadMsgType.FILE_RESULT, sessionId = sessionId
+  )
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.UPLOAD, reqContent, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
 proc codex_download_init(
     ctx: ptr CodexContext,
     cid: cstring,
@@ -473,6 +631,32 @@ proc codex_storage_list(
 
   return callback.okOrError(res, userData)
 
+proc codex_storage_list_page(
+    ctx: ptr CodexContext,
+    cursor: cstring,
+    limit: csize_t,
+    mimetype: cstring,
+    filenamePrefix: cstring,
+    callback: CodexCallback,
+    userData: pointer,
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let req = NodeStorageRequest.createShared(
+    NodeStorageMsgType.LIST_PAGE,
+    cursor = cursor,
+    limit = limit,
+    mimetype = mimetype,
+    filenamePrefix = filenamePrefix,
+  )
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.STORAGE, req, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
 proc codex_storage_space(
     ctx: ptr CodexContext, callback: CodexCallback, userData: pointer
 ): cint {.dynlib, exportc.} =
@@ -529,6 +713,40 @@ proc codex_storage_exists(
 
   return callback.okOrError(res, userData)
 
+proc codex_storage_set_expiry(
+    ctx: ptr CodexContext,
+    cid: cstring,
+    expiry: int64,
+    callback: CodexCallback,
+    userData: pointer,
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let req = NodeStorageRequest.createShared(
+    NodeStorageMsgType.SET_EXPIRY, cid = cid, expiry = expiry
+  )
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.STORAGE, req, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
+proc codex_storage_expiry(
+    ctx: ptr CodexContext, cid: cstring, callback: CodexCallback, userData: pointer
+): cint {.dynlib, exportc.} =
+  initializeLibrary()
+  checkLibcodexParams(ctx, callback, userData)
+
+  let req = NodeStorageRequest.createShared(NodeStorageMsgType.EXPIRY, cid = cid)
+
+  let res = codex_context.sendRequestToCodexThread(
+    ctx, RequestType.STORAGE, req, callback, userData
+  )
+
+  return callback.okOrError(res, userData)
+
 proc codex_start(
     ctx: ptr CodexContext, callback: CodexCallback, userData: pointer
 ): cint {.dynlib, exportc.} =
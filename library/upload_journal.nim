@@ -0,0 +1,137 @@
+{.push raises: [].}
+
+## Durable journal of the upload sessions, allowing to resume them
+## after a restart of the node.
+##
+## Each session has a file in the `uploads` folder of the data dir.
+## The first line is the session header in JSON, then each line is the
+## cid of a block stored for the session, in the order of the dataset.
+## A truncated last line, written when the process crashed, is ignored.
+
+import std/[os, json, times, strutils]
+import chronicles
+import results
+import libp2p/cid
+
+logScope:
+  topics = "codexlib codexlibjournal"
+
+const UploadJournalDir* = "uploads"
+
+type UploadJournal* = object
+  filepath*: string
+  # Overrides of the manifest metadata, empty when not set
+  filename*: string
+  mimetype*: string
+  chunkSize*: int
+  # Expiry of the dataset after the finalization in seconds, 0 when not set
+  ttl*: int64
+  # Announcement of the dataset to the network once stored
+  announce*: bool
+  cids*: seq[Cid]
+  # Unix time of the creation of the session
+  createdAt*: int64
+  # Unix time of the last write
+  updatedAt*: int64
+
+proc journalPath(dir: string, sessionId: string): string =
+  dir / (sessionId & ".journal")
+
+proc nowUnix*(): int64 =
+  getTime().toUnix()
+
+proc exists*(dir: string, sessionId: string): bool =
+  fileExists(journalPath(dir, sessionId))
+
+proc write*(dir: string, sessionId: string, journal: UploadJournal): Result[void, string] =
+  ## Creates the journal of a session, replacing the existing one.
+
+  var content = $(
+    %*{
+      "filepath": journal.filepath,
+      "filename": journal.filename,
+      "mimetype": journal.mimetype,
+      "chunkSize": journal.chunkSize,
+      "ttl": journal.ttl,
+      "announce": journal.announce,
+      "createdAt": journal.createdAt,
+    }
+  )
+  content.add("\n")
+  for cid in journal.cids:
+    content.add($cid & "\n")
+
+  try:
+    createDir(dir)
+    writeFile(journalPath(dir, sessionId), content)
+  except IOError, OSError:
+    let e = getCurrentException()
+    return err("Failed to write the upload journal: " & e.msg)
+
+  return ok()
+
+proc append*(dir: string, sessionId: string, cid: Cid): Result[void, string] =
+  ## Appends the cid of a stored block to the journal of a session.
+  ## The line is flushed before returning.
+
+  var f: File
+  if not f.open(journalPath(dir, sessionId), fmAppend):
+    return err("Failed to open the upload journal of session " & sessionId)
+
+  defer:
+    f.close()
+
+  try:
+    f.writeLine($cid)
+    f.flushFile()
+  except IOError as e:
+    return err("Failed to append to the upload journal: " & e.msg)
+
+  return ok()
+
+proc load*(dir: string, sessionId: string): Result[UploadJournal, string] =
+  let path = journalPath(dir, sessionId)
+  if not fileExists(path):
+    return err("the session is not found: " & sessionId)
+
+  try:
+    let lines = readFile(path).splitLines()
+    let header = parseJson(lines[0])
+
+    var journal = UploadJournal(
+      filepath: header["filepath"].getStr(),
+      filename: header{"filename"}.getStr(),
+      mimetype: header{"mimetype"}.getStr(),
+      chunkSize: header["chunkSize"].getInt(),
+      ttl: header{"ttl"}.getBiggestInt(),
+      announce: header{"announce"}.getBool(true),
+      createdAt: header{"createdAt"}.getBiggestInt(),
+      updatedAt: getLastModificationTime(path).toUnix(),
+    )
+
+    for line in lines[1 ..^ 1]:
+      # Stop at the truncated line, if any
+      let cid = Cid.init(line).valueOr:
+        break
+      journal.cids.add(cid)
+
+    return ok(journal)
+  except CatchableError as e:
+    return err("Failed to read the upload journal: " & e.msg)
+
+proc remove*(dir: string, sessionId: string) =
+  try:
+    removeFile(journalPath(dir, sessionId))
+  except OSError as e:
+    error "Failed to remove the upload journal", sessionId, error = e.msg
+
+proc sessions*(dir: string): seq[string] =
+  ## Returns the ids of the sessions having a journal.
+
+  try:
+    for kind, path in walkDir(dir):
+      let (_, name, ext) = splitFile(path)
+      if kind == pcFile and ext == ".journal":
+        result.add(name)
+  except OSError as e:
+    error "Failed to list the upload journals", error = e.msg
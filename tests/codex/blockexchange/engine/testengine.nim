@@ -1,6 +1,7 @@
 import std/sequtils
 import std/random
 import std/algorithm
+import std/options
 
 import pkg/stew/byteutils
 import pkg/chronos
@@ -191,6 +192,28 @@ asyncchecksuite "NetworkStore engine handlers":
     await engine.wantListHandler(peerId, wantList)
     await done
 
+  test "Should not handle the want list of a rejected peer":
+    let wantList = makeWantList(blocks.mapIt(it.cid), wantType = WantType.WantBlock)
+
+    proc sendPresence(
+        peerId: PeerId, presence: seq[BlockPresence]
+    ) {.async: (raises: [CancelledError]).} =
+      fail()
+
+    engine.network =
+      BlockExcNetwork(request: BlockExcRequest(sendPresence: sendPresence))
+    engine.peerFilter = some PeerFilter(
+      proc(peer: PeerId): bool =
+        peer != peerId
+    )
+
+    await allFuturesThrowing(allFinished(blocks.mapIt(localStore.putBlock(it))))
+
+    await engine.wantListHandler(peerId, wantList)
+
+    check peerCtx.wantedBlocks.len == 0
+    check engine.taskQueue.len == 0
+
   test "Should handle want list - `dont-have`":
     let
       done = newFuture[void]()
@@ -258,6 +281,23 @@ asyncchecksuite "NetworkStore engine handlers":
       let present = await engine.localStore.hasBlock(b.cid)
       check present.tryGet()
 
+  test "Should count the blocks received from the peer":
+    for blk in blocks:
+      peerCtx.blockRequestScheduled(blk.address)
+
+    engine.network = BlockExcNetwork(
+      request: BlockExcRequest(sendWantCancellations: NopSendWantCancellationsProc)
+    )
+
+    await engine.blocksDeliveryHandler(
+      peerId, blocks.mapIt(BlockDelivery(blk: it, address: it.address))
+    )
+
+    check:
+      peerCtx.stats.blocksReceived == blocks.len
+      peerCtx.stats.bytesReceived == blocks.mapIt(it.data.len).foldl(a + b)
+      peerCtx.stats.blocksSent == 0
+
   test "Should send payments for received blocks":
     let
       done = newFuture[void]()
@@ -645,6 +685,25 @@ asyncchecksuite "Task Handler":
 
     await engine.taskHandler(peersCtx[0])
 
+  test "Should count the blocks sent to the peer":
+    proc sendBlocksDelivery(
+        id: PeerId, blocksDelivery: seq[BlockDelivery]
+    ) {.async: (raises: [CancelledError]).} =
+      discard
+
+    for blk in blocks:
+      (await engine.localStore.putBlock(blk)).tryGet()
+    engine.network.request.sendBlocksDelivery = sendBlocksDelivery
+
+    peersCtx[0].wantedBlocks.incl(blocks[0].address)
+    peersCtx[0].wantedBlocks.incl(blocks[1].address)
+
+    await engine.taskHandler(peersCtx[0])
+
+    check:
+      peersCtx[0].stats.blocksSent == 2
+      peersCtx[0].stats.bytesSent == blocks[0].data.len + blocks[1].data.len
+
   test "Should not mark blocks for which local look fails as sent":
     peersCtx[0].wantedBlocks.incl(blocks[0].address)
 
@@ -140,6 +140,73 @@ asyncchecksuite "Test Node - Basic":
       data.len == original.len
       sha256.digest(data) == sha256.digest(original)
 
+  test "Should release the stored blocks when storing is cancelled":
+    var stored = 0
+    let
+      stream = BufferStream.new()
+      storeFut = node.store(
+        stream,
+        blockSize = 1024.NBytes,
+        onBlockStored = proc(chunk: seq[byte]) =
+          stored.inc,
+      )
+
+    var blocks: seq[bt.Block]
+    for i in 0 ..< 2:
+      var chunk = newSeq[byte](1024)
+      for j in 0 ..< chunk.len:
+        chunk[j] = byte((i + j) mod 256)
+
+      blocks.add(bt.Block.new(chunk).tryGet())
+      await stream.pushData(chunk)
+
+    check eventually stored == blocks.len
+    for blk in blocks:
+      check await blk.cid in localStore
+
+    await storeFut.cancelAndWait()
+
+    for blk in blocks:
+      check not (await blk.cid in localStore)
+
+  test "Should resume storing after the stored blocks":
+    var chunks: seq[seq[byte]]
+    for i in 0 ..< 3:
+      var chunk = newSeq[byte](1024)
+      for j in 0 ..< chunk.len:
+        chunk[j] = byte((i * 7 + j) mod 256)
+      chunks.add(chunk)
+
+    let
+      stream = BufferStream.new()
+      storeFut = node.store(stream, blockSize = 1024.NBytes)
+
+    for chunk in chunks:
+      await stream.pushData(chunk)
+    await stream.pushEof()
+    let expected = (await storeFut).tryGet()
+
+    var stored, journaled: seq[Cid]
+    for chunk in chunks[0 ..< 2]:
+      stored.add(bt.Block.new(chunk).tryGet().cid)
+
+    let
+      resumed = BufferStream.new()
+      resumedFut = node.store(
+        resumed,
+        blockSize = 1024.NBytes,
+        stored = stored,
+        onBlockCid = proc(cid: Cid) =
+          journaled.add(cid),
+      )
+
+    await resumed.pushData(chunks[2])
+    await resumed.pushEof()
+
+    check:
+      (await resumedFut).tryGet() == expected
+      journaled == @[bt.Block.new(chunks[2]).tryGet().cid]
+
   test "Should retrieve a Data Stream":
     let
       manifest = await storeDataGetManifest(localStore, chunker)
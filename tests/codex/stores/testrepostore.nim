@@ -278,6 +278,26 @@ asyncchecksuite "RepoStore":
       expectedExpiration in updatedExpirations
       updatedExpectedExpiration notin updatedExpirations
 
+  test "Should set block expiration timestamp even when current expiration is farther":
+    let blk = createTestBlock(100)
+
+    (await repo.putBlock(blk, some 10.seconds)).tryGet
+    check (await repo.getExpiry(blk.cid)).tryGet == now + 10
+
+    (await repo.setExpiry(blk.cid, now + 5)).tryGet
+    check (await repo.getExpiry(blk.cid)).tryGet == now + 5
+
+    (await repo.setExpiry(blk.cid, now + 20)).tryGet
+    check (await repo.getExpiry(blk.cid)).tryGet == now + 20
+
+  test "Should fail when getting expiry of non-existing block":
+    let blk = createTestBlock(100)
+
+    let res = await repo.getExpiry(blk.cid)
+    check:
+      res.isErr
+      res.error of BlockNotFoundError
+
   test "delBlock should remove expiration metadata":
     let
       blk = createTestBlock(100)
@@ -435,6 +455,43 @@ asyncchecksuite "RepoStore":
     (await repo.delBlock(treeCid2, 0.Natural)).tryGet()
     check not (await sharedBlock.cid in repo)
 
+  test "should not lower the expiry of a block shared by two datasets":
+    let
+      repo = RepoStore.new(repoDs, metaDs, clock = mockClock, quotaMaxBytes =
+          1000'nb)
+      blockPool = await makeRandomBlocks(datasetSize = 768, blockSize = 256'nb)
+
+    let
+      dataset1 = @[blockPool[0], blockPool[1]]
+      dataset2 = @[blockPool[1], blockPool[2]]
+
+    let
+      (_, tree1, _) = makeDataset(dataset1).tryGet()
+      treeCid1 = tree1.rootCid.tryGet()
+      (_, tree2, _) = makeDataset(dataset2).tryGet()
+      treeCid2 = tree2.rootCid.tryGet()
+
+    for blk in blockPool:
+      (await repo.putBlock(blk, some 100.seconds)).tryGet()
+
+    for index, blk in dataset1:
+      let proof = tree1.getProof(index).tryGet()
+      (await repo.putCidAndProof(treeCid1, index, blk.cid, proof)).tryGet()
+
+    for index, blk in dataset2:
+      let proof = tree2.getProof(index).tryGet()
+      (await repo.putCidAndProof(treeCid2, index, blk.cid, proof)).tryGet()
+
+    for index in 0 ..< dataset1.len:
+      (await repo.setExpiry(treeCid1, index, now + 10)).tryGet()
+
+    check (await repo.getExpiry(blockPool[0].cid)).tryGet == now + 10
+    check (await repo.getExpiry(blockPool[1].cid)).tryGet == now + 100
+    check (await repo.getExpiry(blockPool[2].cid)).tryGet == now + 100
+
+    (await repo.setExpiry(treeCid2, 0, now + 200)).tryGet()
+    check (await repo.getExpiry(blockPool[1].cid)).tryGet == now + 200
+
   test "should clear leaf metadata when block is deleted from dataset":
     let
       repo = RepoStore.new(repoDs, metaDs, clock = mockClock, quotaMaxBytes =